@@ -4,6 +4,7 @@ go 1.17
 
 require (
 	wego v0.0.0
+	google.golang.org/protobuf v1.27.1 // indirect
 )
 replace (
 	wego => ./wego
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"log"
 	"net/http"
+	"time"
 	"wego"
 )
 
@@ -12,8 +14,11 @@ func main() {
 		c.HTML(http.StatusOK, "<h1>Hello WeGo!</h1>")
 	})
 	e.POST("/hello/:name", func(c *wego.Context) {
-		c.String(http.StatusOK, "hello %s", c.Params["name"])
+		c.String(http.StatusOK, "hello %s", c.Param("name"))
 	})
 
-	_ = e.Run(":8080")
+	//收到SIGINT或SIGTERM后等待处理中的请求结束再退出
+	if err := e.RunGraceful(":8080", 10*time.Second); err != nil {
+		log.Fatal(err)
+	}
 }
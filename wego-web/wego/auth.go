@@ -0,0 +1,154 @@
+package wego
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+)
+
+//Principal 为通过认证的用户或调用方,由认证中间件设定
+type Principal struct {
+	Subject string                 //用户名、JWT的sub或API key对应的调用方
+	Method  string                 //认证方式: basic、jwt、apikey 或自定义的值
+	Scopes  []string               //授权范围
+	Roles   []string               //角色
+	Claims  map[string]interface{} //JWT中的所有声明,其他认证方式为nil
+}
+
+//HasScope 判断是否拥有授权范围scope
+func (p *Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+//HasRole 判断是否拥有角色role
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//Principal 返回认证中间件设定的Principal,未认证时返回nil
+func (c *Context) Principal() *Principal {
+	return c.principal
+}
+
+//SetPrincipal 设定通过认证的Principal,供自定义的认证中间件使用
+func (c *Context) SetPrincipal(p *Principal) {
+	c.principal = p
+}
+
+//RequireScopes 要求拥有所有给出的授权范围,未认证时返回401,缺少授权范围时返回403
+//	需要在认证中间件之后使用
+func RequireScopes(scopes ...string) HandlerFunc {
+	return func(c *Context) {
+		p := c.Principal()
+		if p == nil {
+			c.Fail(http.StatusUnauthorized, "authentication required")
+			return
+		}
+		for _, scope := range scopes {
+			if !p.HasScope(scope) {
+				c.Fail(http.StatusForbidden, "missing scope '"+scope+"'")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+//RequireRoles 要求拥有给出的角色之一,未认证时返回401,没有任何一个角色时返回403
+func RequireRoles(roles ...string) HandlerFunc {
+	return func(c *Context) {
+		p := c.Principal()
+		if p == nil {
+			c.Fail(http.StatusUnauthorized, "authentication required")
+			return
+		}
+		for _, role := range roles {
+			if p.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+		c.Fail(http.StatusForbidden, "permission denied")
+	}
+}
+
+//BasicAuth 使用HTTP基本认证,accounts为用户名到密码的映射
+//	认证失败时返回401与 WWW-Authenticate ,成功时Principal的Subject为用户名
+func BasicAuth(realm string, accounts map[string]string) HandlerFunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+	//比较摘要使比较的时间与密码长度无关
+	digests := make(map[string][32]byte, len(accounts))
+	for user, password := range accounts {
+		digests[user] = sha256.Sum256([]byte(password))
+	}
+	dummy := sha256.Sum256([]byte("wego"))
+	return func(c *Context) {
+		user, password, ok := c.Req.BasicAuth()
+		expected, found := digests[user]
+		if !found {
+			//用户不存在时同样进行一次比较,避免通过响应时间判断用户是否存在
+			expected = dummy
+		}
+		actual := sha256.Sum256([]byte(password))
+		if !ok || subtle.ConstantTimeCompare(actual[:], expected[:]) != 1 || !found {
+			c.SetHeader("WWW-Authenticate", challenge)
+			c.Fail(http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		c.SetPrincipal(&Principal{Subject: user, Method: "basic"})
+		c.Next()
+	}
+}
+
+//APIKeyConfig 为 APIKey 中间件的设定
+type APIKeyConfig struct {
+	//Header 为携带API key的请求头,默认为 X-API-Key
+	Header string
+	//Query 不为空时也从该查询参数中读取API key
+	Query string
+	//Lookup 根据API key返回对应的Principal,key无效时返回false
+	Lookup func(key string) (*Principal, bool)
+}
+
+//APIKey 从请求头或查询参数中读取API key并通过Lookup认证,失败时返回401
+func APIKey(config APIKeyConfig) HandlerFunc {
+	if config.Lookup == nil {
+		panic("wego: APIKey requires a Lookup function")
+	}
+	if config.Header == "" {
+		config.Header = "X-API-Key"
+	}
+	return func(c *Context) {
+		key := c.Req.Header.Get(config.Header)
+		if key == "" && config.Query != "" {
+			key = c.Query(config.Query)
+		}
+		if key == "" {
+			c.Fail(http.StatusUnauthorized, "missing API key")
+			return
+		}
+		p, ok := config.Lookup(key)
+		if !ok || p == nil {
+			c.Fail(http.StatusUnauthorized, "invalid API key")
+			return
+		}
+		if p.Method == "" {
+			p.Method = "apikey"
+		}
+		c.SetPrincipal(p)
+		c.Next()
+	}
+}
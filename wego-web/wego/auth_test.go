@@ -0,0 +1,96 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	r := New()
+	r.Use(BasicAuth("admin", map[string]string{"foo": "bar"}))
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, c.Principal().Subject)
+	})
+
+	for _, tc := range []struct {
+		user, password string
+		code           int
+	}{
+		{"foo", "bar", http.StatusOK},
+		{"foo", "baz", http.StatusUnauthorized},
+		{"bar", "bar", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.password)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Fatalf("%s:%s: expected %d, got %d", tc.user, tc.password, tc.code, w.Code)
+		}
+		if tc.code == http.StatusOK && w.Body.String() != "foo" {
+			t.Fatalf("unexpected principal %q", w.Body.String())
+		}
+		if tc.code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != `Basic realm="admin", charset="UTF-8"` {
+			t.Fatalf("unexpected challenge %q", w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestAPIKeyAndRequire(t *testing.T) {
+	keys := map[string]*Principal{
+		"reader": {Subject: "svc-reader", Scopes: []string{"read"}, Roles: []string{"user"}},
+		"writer": {Subject: "svc-writer", Scopes: []string{"read", "write"}, Roles: []string{"admin"}},
+	}
+	r := New()
+	api := r.Group("/api")
+	api.Use(APIKey(APIKeyConfig{Query: "api_key", Lookup: func(key string) (*Principal, bool) {
+		p, ok := keys[key]
+		return p, ok
+	}}))
+	api.GET("/read", func(c *Context) {
+		c.String(http.StatusOK, c.Principal().Subject+" "+c.Principal().Method)
+	})
+	write := api.Group("/write")
+	write.Use(RequireScopes("read", "write"))
+	write.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "written")
+	})
+	admin := api.Group("/admin")
+	admin.Use(RequireRoles("admin", "owner"))
+	admin.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "admin")
+	})
+	r.GET("/anonymous", RequireScopes("read"), func(c *Context) {
+		c.String(http.StatusOK, "never")
+	})
+
+	for _, tc := range []struct {
+		path, header string
+		code         int
+		body         string
+	}{
+		{"/api/read", "reader", http.StatusOK, "svc-reader apikey"},
+		{"/api/read?api_key=writer", "", http.StatusOK, "svc-writer apikey"},
+		{"/api/read", "", http.StatusUnauthorized, ""},
+		{"/api/read", "unknown", http.StatusUnauthorized, ""},
+		{"/api/write/", "reader", http.StatusForbidden, ""},
+		{"/api/write/", "writer", http.StatusOK, "written"},
+		{"/api/admin/", "reader", http.StatusForbidden, ""},
+		{"/api/admin/", "writer", http.StatusOK, "admin"},
+		{"/anonymous", "", http.StatusUnauthorized, ""},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("X-API-Key", tc.header)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || (tc.body != "" && w.Body.String() != tc.body) {
+			t.Fatalf("%s with %q: expected %d %q, got %d %q", tc.path, tc.header, tc.code, tc.body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package wego
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Binding 将请求中的数据解析到结构体中
+//	解析完成后会使用 Validate 校验结构体的 binding 标签
+type Binding interface {
+	Name() string
+	Bind(req *http.Request, obj interface{}) error
+}
+
+//defaultMultipartMemory 解析multipart表单时使用的默认内存上限
+const defaultMultipartMemory = 32 << 20
+
+var (
+	//BindingJSON 解析JSON格式的请求体,使用 json 标签
+	BindingJSON Binding = jsonBinding{}
+	//BindingXML 解析XML格式的请求体,使用 xml 标签
+	BindingXML Binding = xmlBinding{}
+	//BindingForm 解析url中的参数与 application/x-www-form-urlencoded 请求体,使用 form 标签
+	BindingForm Binding = formBinding{}
+	//BindingFormMultipart 解析url中的参数与 multipart/form-data 请求体,使用 form 标签
+	BindingFormMultipart Binding = multipartBinding{}
+	//BindingQuery 只解析url中的参数,使用 form 标签
+	BindingQuery Binding = queryBinding{}
+	//BindingHeader 解析请求头,使用 header 标签
+	BindingHeader Binding = headerBinding{}
+)
+
+//bindingFor 根据请求方式与Content-Type选择Binding
+func bindingFor(method string, contentType string) Binding {
+	if method == http.MethodGet || method == http.MethodHead {
+		return BindingForm
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return BindingJSON
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return BindingXML
+	case mediaType == "multipart/form-data":
+		return BindingFormMultipart
+	default:
+		return BindingForm
+	}
+}
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string { return "json" }
+
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req.Body == nil {
+		return errors.New("wego: invalid request: empty body")
+	}
+	if err := json.NewDecoder(req.Body).Decode(obj); err != nil {
+		if err == io.EOF {
+			return errors.New("wego: invalid request: empty body")
+		}
+		return err
+	}
+	return validate(obj, "json")
+}
+
+type xmlBinding struct{}
+
+func (xmlBinding) Name() string { return "xml" }
+
+func (xmlBinding) Bind(req *http.Request, obj interface{}) error {
+	if req.Body == nil {
+		return errors.New("wego: invalid request: empty body")
+	}
+	if err := xml.NewDecoder(req.Body).Decode(obj); err != nil {
+		if err == io.EOF {
+			return errors.New("wego: invalid request: empty body")
+		}
+		return err
+	}
+	return validate(obj, "xml")
+}
+
+type formBinding struct{}
+
+func (formBinding) Name() string { return "form" }
+
+func (formBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	if err := mapValues(obj, "form", valuesSource(req.Form)); err != nil {
+		return err
+	}
+	return validate(obj, "form")
+}
+
+type multipartBinding struct{}
+
+func (multipartBinding) Name() string { return "multipart/form-data" }
+
+func (multipartBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		return err
+	}
+	if err := mapValues(obj, "form", valuesSource(req.Form)); err != nil {
+		return err
+	}
+	return validate(obj, "form")
+}
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string { return "query" }
+
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := mapValues(obj, "form", valuesSource(req.URL.Query())); err != nil {
+		return err
+	}
+	return validate(obj, "form")
+}
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string { return "header" }
+
+func (headerBinding) Bind(req *http.Request, obj interface{}) error {
+	source := func(key string) ([]string, bool) {
+		values, ok := req.Header[http.CanonicalHeaderKey(key)]
+		return values, ok
+	}
+	if err := mapValues(obj, "header", source); err != nil {
+		return err
+	}
+	return validate(obj, "header")
+}
+
+//bindURI 将路径参数解析到obj中,使用 uri 标签
+func bindURI(params Params, obj interface{}) error {
+	source := func(key string) ([]string, bool) {
+		if value, ok := params.Get(key); ok {
+			return []string{value}, true
+		}
+		return nil, false
+	}
+	if err := mapValues(obj, "uri", source); err != nil {
+		return err
+	}
+	return validate(obj, "uri")
+}
+
+//valueSource 按名称查找需要绑定的值
+type valueSource func(key string) ([]string, bool)
+
+func valuesSource(values map[string][]string) valueSource {
+	return func(key string) ([]string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+//mapValues 按结构体字段的tag标签从source中取值并赋值
+//	标签格式为 `form:"name,default=value"`,未设置标签时使用字段名
+//	time.Time 字段可以使用 time_format 标签指定格式,默认为 RFC3339
+func mapValues(obj interface{}, tag string, source valueSource) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("wego: binding target must be a non-nil pointer to struct, got %T", obj)
+	}
+	return mapStruct(v.Elem(), tag, source, nil)
+}
+
+//mapStruct 绑定结构体v的字段,parents为正在绑定的外层结构体类型
+func mapStruct(v reflect.Value, tag string, source valueSource, parents []reflect.Type) error {
+	t := v.Type()
+	parents = append(parents, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			//未导出的字段
+			continue
+		}
+		name, opts := splitTag(field.Tag.Get(tag))
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if isNestedStruct(field.Type) {
+			//嵌套结构体的字段与外层字段使用同一个命名空间
+			if err := mapNested(fv, tag, source, parents); err != nil {
+				return err
+			}
+			continue
+		}
+		values, ok := source(fieldName(name, field))
+		if !ok {
+			def, has := opts["default"]
+			if !has {
+				continue
+			}
+			values = []string{def}
+		}
+		if err := setField(fv, field, values); err != nil {
+			return fmt.Errorf("wego: binding field '%s': %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+//isNestedStruct 判断字段是否为需要递归绑定的结构体或结构体指针
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+//mapNested 绑定嵌套结构体或指向结构体的指针
+//	指向外层结构体类型的指针(如 Parent *Category )会形成循环,不进行绑定
+func mapNested(v reflect.Value, tag string, source valueSource, parents []reflect.Type) error {
+	if v.Kind() == reflect.Struct {
+		return mapStruct(v, tag, source, parents)
+	}
+	for _, t := range parents {
+		if t == v.Type().Elem() {
+			return nil
+		}
+	}
+	if !v.IsNil() {
+		return mapStruct(v.Elem(), tag, source, parents)
+	}
+	if !v.CanSet() {
+		return nil
+	}
+	//只有嵌套结构体中有字段被赋值时才分配指针
+	elem := reflect.New(v.Type().Elem())
+	changed := false
+	probe := func(key string) ([]string, bool) {
+		values, ok := source(key)
+		changed = changed || ok
+		return values, ok
+	}
+	if err := mapStruct(elem.Elem(), tag, probe, parents); err != nil {
+		return err
+	}
+	if changed {
+		v.Set(elem)
+	}
+	return nil
+}
+
+func fieldName(name string, field reflect.StructField) string {
+	if name != "" {
+		return name
+	}
+	return field.Name
+}
+
+//splitTag 将标签拆分为名称与 key=value 形式的选项
+func splitTag(tag string) (string, map[string]string) {
+	parts := strings.Split(tag, ",")
+	opts := make(map[string]string, len(parts)-1)
+	for _, opt := range parts[1:] {
+		if i := strings.IndexByte(opt, '='); i >= 0 {
+			opts[opt[:i]] = opt[i+1:]
+		} else {
+			opts[opt] = ""
+		}
+	}
+	return parts[0], opts
+}
+
+//setField 将values赋值给字段,切片字段接收全部的值,其他字段只使用第一个值
+func setField(v reflect.Value, field reflect.StructField, values []string) error {
+	if !v.CanSet() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && len(values) == 1 {
+			//[]byte 按字符串处理
+			v.SetBytes([]byte(values[0]))
+			return nil
+		}
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), field, value); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(values) != v.Len() {
+			return fmt.Errorf("%d values can't be assigned to %s", len(values), v.Type())
+		}
+		for i, value := range values {
+			if err := setValue(v.Index(i), field, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return setValue(v, field, values[0])
+}
+
+//setValue 将字符串转换为字段对应的类型后赋值
+func setValue(v reflect.Value, field reflect.StructField, value string) error {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), field, value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if v.Type() == timeType {
+		return setTime(v, field, value)
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		if value == "" {
+			value = "false"
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			value = "0"
+		}
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func setTime(v reflect.Value, field reflect.StructField, value string) error {
+	if value == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	layout := field.Tag.Get("time_format")
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
@@ -0,0 +1,150 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	City string `json:"city" form:"city" binding:"required"`
+}
+
+type testUser struct {
+	Name    string        `json:"name" form:"name" binding:"required,min=2,max=8"`
+	Email   string        `json:"email" form:"email" binding:"omitempty,email"`
+	Role    string        `json:"role" form:"role,default=user" binding:"oneof=user admin"`
+	Age     int           `json:"age" form:"age" binding:"min=0,max=150"`
+	Tags    []string      `json:"tags" form:"tags" binding:"max=3,dive,min=1"`
+	Address testAddress   `json:"address"`
+	Friends []testAddress `json:"friends"`
+}
+
+func TestBindForm(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?tags=a&tags=b", strings.NewReader("name=wego&age=18&city=sh"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var u testUser
+	if err := bindingFor(req.Method, req.Header.Get("Content-Type")).Bind(req, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "wego" || u.Age != 18 || u.Role != "user" || len(u.Tags) != 2 || u.Address.City != "sh" {
+		t.Fatalf("unexpected binding result: %+v", u)
+	}
+}
+
+type testCategory struct {
+	Name   string        `form:"name"`
+	Parent *testCategory //指向自身类型的指针不会被绑定
+	Owner  *struct {
+		Email string `form:"owner_email"`
+	}
+}
+
+func TestBindFormNestedPointer(t *testing.T) {
+	done := make(chan struct{})
+	var c testCategory
+	var err error
+	go func() {
+		req := httptest.NewRequest(http.MethodGet, "/?name=go", nil)
+		err = BindingForm.Bind(req, &c)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("binding a self-referencing struct shouldn't recurse forever")
+	}
+	if err != nil || c.Name != "go" || c.Parent != nil || c.Owner != nil {
+		t.Fatalf("unexpected binding result: %+v %v", c, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?name=go&owner_email=a@b.c", nil)
+	if err := BindingForm.Bind(req, &c); err != nil || c.Owner == nil || c.Owner.Email != "a@b.c" {
+		t.Fatalf("unexpected binding result: %+v %v", c, err)
+	}
+}
+
+func TestBindJSONValidation(t *testing.T) {
+	body := `{"name":"w","email":"bad","role":"root","tags":["a",""],"address":{},"friends":[{"city":"bj"},{}]}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	var u testUser
+	err := bindingFor(req.Method, req.Header.Get("Content-Type")).Bind(req, &u)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("ValidationErrors should be returned, got %v", err)
+	}
+	want := []string{
+		"name:min", "email:email", "role:oneof",
+		"tags[1]:min", "address.city:required", "friends[1].city:required",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i, e := range errs {
+		if e.Field+":"+e.Tag != want[i] {
+			t.Fatalf("error %d should be %s, got %s", i, want[i], e.Field+":"+e.Tag)
+		}
+	}
+}
+
+func TestValidateFieldNames(t *testing.T) {
+	if err := Validate(nil); err != nil {
+		t.Fatalf("Validate(nil) should return nil, got %v", err)
+	}
+	type Page struct {
+		Size int `json:"size" form:"page_size" binding:"max=100"`
+	}
+	type query struct {
+		Page
+		Keyword string `json:"q" form:"q" binding:"required"`
+		Sort    string `binding:"omitempty,oneof=asc desc"`
+	}
+	obj := query{Page: Page{Size: 1000}, Sort: "random"}
+	//表单绑定使用form标签中的名称,嵌入的结构体的字段位于同一层
+	for tag, want := range map[string]string{
+		"json": "size:max q:required Sort:oneof",
+		"form": "page_size:max q:required Sort:oneof",
+	} {
+		errs, _ := validate(&obj, tag).(ValidationErrors)
+		got := make([]string, len(errs))
+		for i, e := range errs {
+			got[i] = e.Field + ":" + e.Tag
+		}
+		if strings.Join(got, " ") != want {
+			t.Fatalf("%s: expected %q, got %q", tag, want, strings.Join(got, " "))
+		}
+	}
+}
+
+func TestBindHeaderAndURI(t *testing.T) {
+	type request struct {
+		ID      int           `uri:"id" binding:"required"`
+		Token   string        `header:"X-Token" binding:"required"`
+		Since   time.Time     `header:"X-Since" time_format:"2006-01-02"`
+		Timeout time.Duration `header:"x-timeout"`
+	}
+	r := New()
+	r.GET("/items/:id", func(c *Context) {
+		var req request
+		if err := c.ShouldBindURI(&req); err == nil {
+			t.Fatal("header fields should be required when binding uri")
+		}
+		if err := c.ShouldBindHeader(&req); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%d %s %s %s", req.ID, req.Token, req.Since.Format("01/02"), req.Timeout)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/items/7", nil)
+	req.Header.Set("X-Token", "abc")
+	req.Header.Set("X-Since", "2021-10-01")
+	req.Header.Set("X-Timeout", "2s")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "7 abc 10/01 2s" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,297 @@
+package wego
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//CompressConfig 为 Compress 中间件的设定,零值字段使用默认值
+type CompressConfig struct {
+	//Level 为压缩级别,默认为 gzip.DefaultCompression
+	Level int
+	//MinLength 为需要压缩的最小响应体字节数,默认为1024,flush的流式响应总是压缩
+	MinLength int
+	//ExcludedPaths 为不压缩的路径前缀
+	ExcludedPaths []string
+	//ExcludedContentTypes 为不压缩的Content-Type前缀,默认为图片、音视频与常见的压缩格式
+	ExcludedContentTypes []string
+	//DecompressRequest 解压 Content-Encoding 为gzip的请求体
+	DecompressRequest bool
+}
+
+var defaultExcludedContentTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-bzip2",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/x-protobuf",
+}
+
+//Compress 根据请求头Accept-Encoding使用gzip或deflate压缩响应体
+func Compress(config CompressConfig) HandlerFunc {
+	if config.Level == 0 {
+		config.Level = gzip.DefaultCompression
+	}
+	if config.MinLength <= 0 {
+		config.MinLength = 1024
+	}
+	if config.ExcludedContentTypes == nil {
+		config.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+	if _, err := gzip.NewWriterLevel(io.Discard, config.Level); err != nil {
+		panic("wego: " + err.Error())
+	}
+	gzipPool := sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, config.Level)
+		return w
+	}}
+	zlibPool := sync.Pool{New: func() interface{} {
+		w, _ := zlib.NewWriterLevel(io.Discard, config.Level)
+		return w
+	}}
+
+	return func(c *Context) {
+		for _, prefix := range config.ExcludedPaths {
+			if strings.HasPrefix(c.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+		if config.DecompressRequest && !c.decompressRequest() {
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.Req.Header.Get("Accept-Encoding"))
+		if encoding == "" || c.Method == http.MethodHead || c.Req.Header.Get("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: c.Writer, config: &config, encoding: encoding}
+		switch encoding {
+		case "gzip":
+			cw.newWriter = func(w io.Writer) compressor {
+				gz := gzipPool.Get().(*gzip.Writer)
+				gz.Reset(w)
+				return gz
+			}
+			cw.release = func(z compressor) { gzipPool.Put(z) }
+		default:
+			cw.newWriter = func(w io.Writer) compressor {
+				zw := zlibPool.Get().(*zlib.Writer)
+				zw.Reset(w)
+				return zw
+			}
+			cw.release = func(z compressor) { zlibPool.Put(z) }
+		}
+		c.Writer = cw
+		defer func() {
+			cw.finish()
+			c.Writer = cw.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+//decompressRequest 解压gzip请求体,请求体无效时返回400并返回false
+func (c *Context) decompressRequest() bool {
+	if !strings.EqualFold(c.Req.Header.Get("Content-Encoding"), "gzip") || c.Req.Body == nil || c.Req.Body == http.NoBody {
+		return true
+	}
+	gz, err := gzip.NewReader(c.Req.Body)
+	if err != nil {
+		c.Fail(http.StatusBadRequest, "invalid gzip request body")
+		return false
+	}
+	var body io.ReadCloser = &gzipBody{Reader: gz, body: c.Req.Body}
+	if max := c.engine.MaxRequestBodySize; max > 0 {
+		//限制解压后的大小,防止压缩炸弹
+		body = &limitedBody{rc: body, remaining: max}
+	}
+	c.Req.Body = body
+	c.Req.Header.Del("Content-Encoding")
+	c.Req.Header.Del("Content-Length")
+	c.Req.ContentLength = -1
+	return true
+}
+
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+//negotiateEncoding 返回Accept-Encoding中可以使用的编码,q值相同时优先使用gzip
+func negotiateEncoding(accept string) string {
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		if q := encodingQuality(accept, coding); q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+//encodingQuality 返回Accept-Encoding中coding的q值,为0表示不接受
+//	* 只匹配没有明确列出的编码,因此 gzip;q=0, * 不接受gzip
+func encodingQuality(accept, coding string) float64 {
+	wildcard := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		name, q := parseCoding(part)
+		if name == coding {
+			return q
+		}
+		if name == "*" {
+			wildcard = q
+		}
+	}
+	return wildcard
+}
+
+//parseCoding 解析Accept-Encoding中的一项,返回小写的编码与q值
+func parseCoding(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	q := 1.0
+	for _, param := range fields[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") || strings.HasPrefix(param, "Q=") {
+			if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(fields[0])), q
+}
+
+//compressor 为gzip.Writer与zlib.Writer共同的方法
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+//compressWriter 缓存响应体的开头部分,根据长度与Content-Type决定是否压缩
+type compressWriter struct {
+	ResponseWriter
+	config    *CompressConfig
+	encoding  string
+	newWriter func(w io.Writer) compressor
+	release   func(z compressor)
+
+	buf     []byte
+	decided bool
+	z       compressor //为nil时不压缩
+}
+
+//decide 决定是否压缩并写出缓存的数据,flushing为true时不检查长度
+func (w *compressWriter) decide(flushing bool) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		//压缩后net/http无法再根据内容判断类型
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.shouldCompress(flushing) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			//压缩后的内容与原内容不再逐字节相同,强ETag改为弱ETag
+			header.Set("ETag", "W/"+etag)
+		}
+		w.z = w.newWriter(w.ResponseWriter)
+		if len(w.buf) > 0 {
+			w.z.Write(w.buf)
+		}
+	} else if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+}
+
+func (w *compressWriter) shouldCompress(flushing bool) bool {
+	if !flushing && len(w.buf) < w.config.MinLength {
+		return false
+	}
+	if !bodyAllowedForStatus(w.Status()) || w.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	if w.Status() == http.StatusPartialContent || w.Header().Get("Content-Range") != "" {
+		//Range响应的Content-Range按原内容计算,压缩后无法对应
+		return false
+	}
+	contentType := strings.ToLower(w.Header().Get("Content-Type"))
+	for _, excluded := range w.config.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.config.MinLength {
+			return len(data), nil
+		}
+		w.decide(false)
+		return len(data), nil
+	}
+	if w.z != nil {
+		return w.z.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+//Written 缓存了数据后即视为已写入,此时不能再修改状态码
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	w.decide(false)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+//Flush 流式响应在每次flush时发送已压缩的数据
+func (w *compressWriter) Flush() {
+	w.decide(true)
+	if w.z != nil {
+		w.z.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+//finish 写出缓存的数据并结束压缩
+func (w *compressWriter) finish() {
+	if !w.decided && len(w.buf) == 0 {
+		//处理器没有写入响应体
+		return
+	}
+	w.decide(false)
+	if w.z != nil {
+		w.z.Close()
+		w.release(w.z)
+		w.z = nil
+	}
+}
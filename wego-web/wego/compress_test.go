@@ -0,0 +1,128 @@
+package wego
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("wego ", 500)
+	r := New()
+	r.Use(Compress(CompressConfig{ExcludedPaths: []string{"/raw"}, DecompressRequest: true}))
+	r.GET("/large", func(c *Context) {
+		c.String(http.StatusOK, large)
+	})
+	r.GET("/small", func(c *Context) {
+		c.String(http.StatusOK, "small")
+	})
+	r.GET("/raw", func(c *Context) {
+		c.String(http.StatusOK, large)
+	})
+	r.GET("/image", func(c *Context) {
+		c.SetHeader("Content-Type", "image/png")
+		c.Data(http.StatusOK, []byte(large))
+	})
+	r.GET("/stream", func(c *Context) {
+		c.SSEvent("message", "hello")
+	})
+	r.POST("/echo", func(c *Context) {
+		data, err := io.ReadAll(c.Req.Body)
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.Data(http.StatusOK, data)
+	})
+
+	decode := func(encoding string, body io.Reader) string {
+		var reader io.Reader
+		var err error
+		switch encoding {
+		case "gzip":
+			reader, err = gzip.NewReader(body)
+		case "deflate":
+			reader, err = zlib.NewReader(body)
+		default:
+			reader = body
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(reader)
+		return string(data)
+	}
+
+	for _, tc := range []struct {
+		path, accept, encoding, body string
+	}{
+		{"/large", "gzip, deflate", "gzip", large},
+		{"/large", "gzip;q=0.5, deflate", "deflate", large},
+		{"/large", "br, identity", "", large},
+		{"/large", "gzip;q=0", "", large},
+		//* 不匹配明确拒绝的gzip
+		{"/large", "gzip;q=0, *", "deflate", large},
+		{"/large", "*;q=0.5, deflate;q=0.8", "deflate", large},
+		{"/large", "*", "gzip", large},
+		{"/small", "gzip", "", "small"},
+		{"/raw", "gzip", "", large},
+		{"/image", "gzip", "", large},
+		//flush的流式响应即使很短也会被压缩
+		{"/stream", "gzip", "gzip", "event:message\ndata:hello\n\n"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Accept-Encoding", tc.accept)
+		r.ServeHTTP(w, req)
+		if w.Header().Get("Content-Encoding") != tc.encoding || decode(tc.encoding, w.Body) != tc.body {
+			t.Fatalf("%s with %q: unexpected encoding %q", tc.path, tc.accept, w.Header().Get("Content-Encoding"))
+		}
+		if tc.path != "/raw" && w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("%s: Vary should be set, got %v", tc.path, w.Header())
+		}
+	}
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write([]byte("compressed request"))
+	gz.Close()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/echo", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "compressed request" {
+		t.Fatalf("gzip request body should be decompressed, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestCompressStatic(t *testing.T) {
+	large := strings.Repeat("wego ", 500)
+	r := New()
+	r.Use(Compress(CompressConfig{}))
+	r.StaticFS("/assets", fstest.MapFS{"app.js": {Data: []byte(large)}})
+
+	w := serveStatic(r, http.MethodGet, "/assets/app.js", map[string]string{"Accept-Encoding": "gzip"})
+	etag := w.Header().Get("ETag")
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("compressed responses should have a weak ETag, got %v", w.Header())
+	}
+	if w = serveStatic(r, http.MethodGet, "/assets/app.js", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Fatalf("the weak ETag should match If-None-Match, got %d", w.Code)
+	}
+	//Range响应不压缩
+	w = serveStatic(r, http.MethodGet, "/assets/app.js", map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-1999"})
+	if w.Code != http.StatusPartialContent || w.Header().Get("Content-Encoding") != "" || w.Body.String() != large[:2000] || w.Header().Get("Content-Range") == "" {
+		t.Fatalf("range responses should not be compressed, got %d %v", w.Code, w.Header())
+	}
+	//弱ETag不满足If-Range的强比较,返回完整的内容
+	w = serveStatic(r, http.MethodGet, "/assets/app.js", map[string]string{"Range": "bytes=0-9", "If-Range": etag})
+	if w.Code != http.StatusOK {
+		t.Fatalf("If-Range with a weak ETag should return the full content, got %d", w.Code)
+	}
+}
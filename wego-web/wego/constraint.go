@@ -0,0 +1,158 @@
+package wego
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//参数约束: 路由模式中的参数可以使用 :name<约束> 限定能够匹配的值
+//	/users/:id<int>             内置约束,只匹配整数
+//	/files/:name<[a-z0-9-]+>    其他约束视为正则表达式,需要完整匹配参数值
+//	不满足约束的路径会继续尝试匹配其他路由,全部失败时返回404
+
+//paramConstraints 内置的参数约束
+var paramConstraints = map[string]func(string) bool{
+	"int":   isInt,
+	"uint":  isUint,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"uuid":  isUUID,
+}
+
+//parseWildcard 将参数或通配部分解析为参数名与约束
+func parseWildcard(wild string) (key string, constraint string, err error) {
+	key = wild[1:]
+	if i := strings.IndexByte(key, '<'); i >= 0 {
+		if key[len(key)-1] != '>' {
+			return "", "", fmt.Errorf("unterminated constraint in '%s'", wild)
+		}
+		key, constraint = key[:i], key[i+1:len(key)-1]
+		if constraint == "" {
+			return "", "", fmt.Errorf("empty constraint in '%s'", wild)
+		}
+		if wild[0] == '*' {
+			return "", "", fmt.Errorf("catch-all '%s' can't have a constraint", wild)
+		}
+	}
+	if strings.ContainsAny(key, ":*<>") {
+		return "", "", fmt.Errorf("only one wildcard per path segment is allowed, has '%s'", wild)
+	}
+	if wild[0] == ':' && key == "" {
+		return "", "", fmt.Errorf("wildcards must be named with a non-empty name, has '%s'", wild)
+	}
+	return key, constraint, nil
+}
+
+//compileConstraint 返回约束对应的匹配函数,非内置约束按正则表达式编译
+func compileConstraint(constraint string) (func(string) bool, error) {
+	if match, ok := paramConstraints[constraint]; ok {
+		return match, nil
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint <%s>: %v", constraint, err)
+	}
+	return re.MatchString, nil
+}
+
+func isInt(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return isUint(s)
+}
+
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; (c < 'a' || c > 'z') && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUID(s string) bool {
+	_, err := ParseUUID(s)
+	return err == nil
+}
+
+//UUID 为 RFC 4122 格式的UUID
+type UUID [16]byte
+
+//ParseUUID 解析 xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx 格式的UUID,不区分大小写
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("wego: invalid UUID %q", s)
+	}
+	for i, j := 0, 0; i < len(s); {
+		if s[i] == '-' {
+			i++
+			continue
+		}
+		hi, ok1 := fromHexChar(s[i])
+		lo, ok2 := fromHexChar(s[i+1])
+		if !ok1 || !ok2 {
+			return u, fmt.Errorf("wego: invalid UUID %q", s)
+		}
+		u[j] = hi<<4 | lo
+		i, j = i+2, j+1
+	}
+	return u, nil
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+//String 返回UUID的小写标准格式
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
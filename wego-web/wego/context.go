@@ -1,45 +1,116 @@
 package wego
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 //H 为map[string]interface{}起的别名wego.H
 //构建JSON数据时更加简洁
 type H map[string]interface{}
 
+//Param 为一个路径参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+//Params 为路由匹配到的路径参数,按在路由模式中出现的顺序排列
+type Params []Param
+
+//Get 返回名为name的参数值,以及该参数是否存在
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+//ByName 返回名为name的参数值,不存在时返回空字符串
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
+
 //Context 为上下文,封装请求信息
+//	Context由engine的对象池复用,请求处理结束后不应再继续持有
 type Context struct {
 	//封装原有项目
-	Writer http.ResponseWriter
-	Req    *http.Request
+	writermem responseWriter
+	Writer    ResponseWriter //记录状态码与写入字节数的ResponseWriter,状态码通过 Writer.Status() 获取
+	Req       *http.Request
 	//请求信息
 	Path   string
 	Method string
-	Params map[string]string
-	//返回信息
-	StatusCode int
+	Params Params
+	//fullPath 为匹配到的路由模式
+	fullPath string
 	//中间件
 	handlers []HandlerFunc
 	index    int
 	//engine 指针
 	engine *Engine
+	//body 限制请求体大小,设定了 Engine.MaxRequestBodySize 时替换Req.Body
+	body limitedBody
+	//session 为 Sessions 中间件加载的会话
+	session *Session
+	//csrfToken 与 csrfField 由 CSRF 中间件设定
+	csrfToken string
+	csrfField string
+	//cspNonce 由 Secure 中间件设定
+	cspNonce string
+	//principal 由认证中间件设定
+	principal *Principal
+	//errs 为处理请求时发生的错误,记录在访问日志中
+	errs []error
+	//errorHandled 在外层有 ErrorHandler 时为true,此时错误响应由其统一返回
+	errorHandled bool
+	//keys 为请求范围内的键值对,由mu保护
+	mu   sync.RWMutex
+	keys map[string]interface{}
 }
 
-//newContext 是 Context 的构造器
-func newContext(w http.ResponseWriter, req *http.Request) *Context {
+//newContext 是 Context 的构造器,Context由engine的对象池复用
+func newContext(engine *Engine) *Context {
 	return &Context{
-		Writer: w,
-		Req:    req,
-		Path:   req.URL.Path,
-		Method: req.Method,
-		index:  -1, //中间件执行位置,初始化为-1
+		Params: make(Params, 0, engine.router.maxParams),
+		engine: engine,
 	}
 }
 
+//reset 使用新的请求重置从对象池中取出的Context
+func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
+	c.writermem.reset(w, c.engine.Log)
+	c.Writer = &c.writermem
+	c.body = limitedBody{}
+	if max := c.engine.MaxRequestBodySize; max > 0 && req.Body != nil && req.Body != http.NoBody {
+		c.body = limitedBody{rc: req.Body, remaining: max}
+		req.Body = &c.body
+	}
+	c.Req = req
+	c.Path = req.URL.Path
+	c.Method = req.Method
+	c.Params = c.Params[:0]
+	c.fullPath = ""
+	c.handlers = nil
+	c.session = nil
+	c.csrfToken, c.csrfField, c.cspNonce = "", "", ""
+	c.principal = nil
+	c.errs = c.errs[:0]
+	c.keys = nil
+	c.index = -1 //中间件执行位置,初始化为-1
+}
+
 //Next 开始执行c所包含的中间件
 func (c *Context) Next() {
 	//关于为什么要把中间件执行的index保存在c中:
@@ -58,18 +129,81 @@ func (c *Context) Next() {
 	}
 }
 
+//abortIndex Abort 后 index 被设为该值,使 Next 不再执行后续的处理器
+const abortIndex = math.MaxInt16
+
+//Abort 中断中间件的执行,使后面的中间件不再继续执行,当前处理器仍会执行完毕
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+//IsAborted 返回处理链是否已被中断
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+//AbortWithStatus 中断处理链并立即发送状态码
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Writer.WriteHeaderNow()
+	c.Abort()
+}
+
+//AbortWithStatusJSON 中断处理链并返回json格式的对象
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.JSON(code, obj)
+}
+
 //Fail 中断中间件的执行,使后面的中间件不再继续执行,并返回错误信息
 func (c *Context) Fail(code int, err string) {
-	log.Printf("Handler fail at %s handlers[%d] : %s", c.Path, c.index, err)
-	c.JSON(code, H{"message": err})
+	c.engine.Log.Warnf("Handler fail at %q handlers[%d] : %s", c.Path, c.index, err)
+	c.errs = append(c.errs, errors.New(err))
+	c.AbortWithStatusJSON(code, H{"message": err})
+}
+
+//FullPath 返回匹配到的路由模式,如 /users/:id ,未匹配到路由时返回空字符串
+func (c *Context) FullPath() string {
+	return c.fullPath
 }
 
+//ClientIP 返回客户端的IP,只使用连接的远端地址,不信任 X-Forwarded-For 等可以被客户端伪造的请求头
+//	位于反向代理之后时返回的是代理的地址,此时应在 KeyFunc 等处自行读取可信代理设定的请求头
+func (c *Context) ClientIP() string {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		return c.Req.RemoteAddr
+	}
+	return host
+}
+
+//Param 返回路径参数的值
 func (c *Context) Param(key string) string {
-	value, _ := c.Params[key]
-	return value
+	return c.Params.ByName(key)
+}
+
+//ParamInt 将路径参数解析为int,通常与 :name<int> 约束配合使用
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Param(key))
+}
+
+//ParamInt64 将路径参数解析为int64
+func (c *Context) ParamInt64(key string) (int64, error) {
+	return strconv.ParseInt(c.Param(key), 10, 64)
+}
+
+//ParamUint 将路径参数解析为uint64,通常与 :name<uint> 约束配合使用
+func (c *Context) ParamUint(key string) (uint64, error) {
+	return strconv.ParseUint(c.Param(key), 10, 64)
+}
+
+//ParamUUID 将路径参数解析为UUID,通常与 :name<uuid> 约束配合使用
+func (c *Context) ParamUUID(key string) (UUID, error) {
+	return ParseUUID(c.Param(key))
 }
 
 func (c *Context) PostForm(key string) string {
+	c.parseForm()
 	return c.Req.FormValue(key)
 }
 
@@ -78,9 +212,94 @@ func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
 }
 
-//Status 设定Context的状态码
+//ShouldBind 根据请求方式与Content-Type选择Binding,将请求数据解析到obj中并校验
+//	GET请求解析url中的参数,其他请求按Content-Type解析JSON、XML、表单或multipart表单
+func (c *Context) ShouldBind(obj interface{}) error {
+	return c.ShouldBindWith(obj, bindingFor(c.Method, c.Req.Header.Get("Content-Type")))
+}
+
+//ShouldBindWith 使用指定的Binding解析请求数据
+func (c *Context) ShouldBindWith(obj interface{}, b Binding) error {
+	if b == BindingFormMultipart {
+		//预先解析以使用 Engine.MaxMultipartMemory
+		if err := c.parseMultipart(); err != nil {
+			return err
+		}
+	}
+	return c.bodyError(b.Bind(c.Req, obj))
+}
+
+//ShouldBindJSON 将JSON格式的请求体解析到obj中
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingJSON)
+}
+
+//ShouldBindXML 将XML格式的请求体解析到obj中
+func (c *Context) ShouldBindXML(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingXML)
+}
+
+//ShouldBindQuery 将url中的参数解析到obj中
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingQuery)
+}
+
+//ShouldBindHeader 将请求头解析到obj中
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingHeader)
+}
+
+//ShouldBindURI 将路径参数解析到obj中
+func (c *Context) ShouldBindURI(obj interface{}) error {
+	return bindURI(c.Params, obj)
+}
+
+//Bind 与 ShouldBind 相同,解析或校验失败时返回400错误
+func (c *Context) Bind(obj interface{}) error {
+	return c.bindOrFail(c.ShouldBind(obj))
+}
+
+//BindWith 与 ShouldBindWith 相同,解析或校验失败时返回400错误
+func (c *Context) BindWith(obj interface{}, b Binding) error {
+	return c.bindOrFail(c.ShouldBindWith(obj, b))
+}
+
+//BindJSON 与 ShouldBindJSON 相同,解析或校验失败时返回400错误
+func (c *Context) BindJSON(obj interface{}) error {
+	return c.bindOrFail(c.ShouldBindJSON(obj))
+}
+
+//BindXML 与 ShouldBindXML 相同,解析或校验失败时返回400错误
+func (c *Context) BindXML(obj interface{}) error {
+	return c.bindOrFail(c.ShouldBindXML(obj))
+}
+
+//BindQuery 与 ShouldBindQuery 相同,解析或校验失败时返回400错误
+func (c *Context) BindQuery(obj interface{}) error {
+	return c.bindOrFail(c.ShouldBindQuery(obj))
+}
+
+//BindHeader 与 ShouldBindHeader 相同,解析或校验失败时返回400错误
+func (c *Context) BindHeader(obj interface{}) error {
+	return c.bindOrFail(c.ShouldBindHeader(obj))
+}
+
+//BindURI 与 ShouldBindURI 相同,解析或校验失败时返回400错误
+func (c *Context) BindURI(obj interface{}) error {
+	return c.bindOrFail(c.ShouldBindURI(obj))
+}
+
+func (c *Context) bindOrFail(err error) error {
+	if err == ErrBodyTooLarge {
+		c.Fail(http.StatusRequestEntityTooLarge, err.Error())
+	} else if err != nil {
+		c.Fail(http.StatusBadRequest, err.Error())
+	}
+	return err
+}
+
+//Status 设定Context的状态码,状态码在第一次写入响应体时才会发送
 func (c *Context) Status(code int) {
-	c.StatusCode = code
 	c.Writer.WriteHeader(code)
 }
 
@@ -96,19 +315,83 @@ func (c *Context) String(code int, format string, values ...interface{}) {
 	c.Writer.Write([]byte(fmt.Sprintf(format, values...)))
 }
 
+//Render 设定状态码并使用r写入响应
+//	编码失败且尚未写入响应时返回500,状态码不允许包含响应体时只写入响应头
+func (c *Context) Render(code int, r Render) {
+	c.Status(code)
+	if !bodyAllowedForStatus(code) {
+		r.WriteContentType(c.Writer)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	if err := r.Render(c.Writer); err != nil {
+		c.engine.Log.Errorf("render %T: %v", r, err)
+		c.errs = append(c.errs, err)
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, H{"message": http.StatusText(http.StatusInternalServerError)})
+			return
+		}
+		c.Abort()
+	}
+}
+
+//bodyAllowedForStatus 判断状态码是否允许包含响应体
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
 //JSON 返回json格式的对象
 func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Content-Type", "application/json")
-	c.Status(code)
-	//编码器
-	encoder := json.NewEncoder(c.Writer)
-	//Context.ResponseWriter中的Set/WriteHeader/Write这三个方法时
-	//顺序必须为Set/WriteHeader/Write
-	//此处错误处理很不方便,无法重新设置状态码
-	//Gin中直接抛出panic
-	if err := encoder.Encode(obj); err != nil {
-		panic(err.Error())
+	c.Render(code, JSONRender{Data: obj})
+}
+
+//IndentedJSON 返回缩进后的json,便于阅读,但比 JSON 占用更多的带宽
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, IndentedJSONRender{Data: obj})
+}
+
+//SecureJSON 返回json,数据为数组时在前面加上 Engine.SecureJSONPrefix ,防止json劫持
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	c.Render(code, SecureJSONRender{Prefix: c.engine.SecureJSONPrefix, Data: obj})
+}
+
+//JSONP 返回jsonp,回调函数名取自查询参数 callback ,未提供时返回普通的json,回调函数名不合法时返回400
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback != "" && !ValidJSONPCallback(callback) {
+		c.Fail(http.StatusBadRequest, "invalid jsonp callback")
+		return
 	}
+	c.Render(code, JSONPRender{Callback: callback, Data: obj})
+}
+
+//AsciiJSON 返回只包含ASCII字符的json
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	c.Render(code, AsciiJSONRender{Data: obj})
+}
+
+//XML 返回xml格式的对象
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, XMLRender{Data: obj})
+}
+
+//YAML 返回yaml格式的对象
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, YAMLRender{Data: obj})
+}
+
+//ProtoBuf 返回protobuf格式的数据,obj必须为proto.Message
+func (c *Context) ProtoBuf(code int, obj interface{}) {
+	c.Render(code, ProtoBufRender{Data: obj})
 }
 
 //Data 返回字符数组类型的数据
@@ -123,12 +406,14 @@ func (c *Context) HTML(code int, html string) {
 	c.Writer.Write([]byte(html))
 }
 
+//HTMLTemplate 使用 Engine.HTMLRender 渲染名为name的模板
+//	模板渲染成功后才写入响应,渲染失败时返回500
 func (c *Context) HTMLTemplate(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		c.Fail(http.StatusInternalServerError, err.Error())
+	if c.engine.HTMLRender == nil {
+		c.Render(code, errorRender{fmt.Errorf("wego: no HTMLRender, load templates before rendering %q", name)})
+		return
 	}
+	c.Render(code, c.engine.HTMLRender.Instance(name, data))
 }
 
 func (c *Context) Cookie(name string) (*http.Cookie, error) {
@@ -148,6 +433,176 @@ func (c *Context) SetCookie(name, value string, maxAge int, path string, domain
 	http.SetCookie(c.Writer, cookie)
 }
 
+//Set 保存请求范围内的键值对,可以在中间件与处理器之间传递数据,可以并发调用
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	if c.keys == nil {
+		c.keys = make(map[string]interface{})
+	}
+	c.keys[key] = value
+	c.mu.Unlock()
+}
+
+//Get 返回key对应的值,以及该值是否存在
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	value, exists = c.keys[key]
+	c.mu.RUnlock()
+	return
+}
+
+//MustGet 返回key对应的值,不存在时panic
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("wego: key \"" + key + "\" does not exist")
+}
+
+//以下Get*方法在值不存在或类型不符时返回零值
+
+func (c *Context) GetString(key string) (s string) {
+	if v, ok := c.Get(key); ok {
+		s, _ = v.(string)
+	}
+	return
+}
+
+func (c *Context) GetBool(key string) (b bool) {
+	if v, ok := c.Get(key); ok {
+		b, _ = v.(bool)
+	}
+	return
+}
+
+func (c *Context) GetInt(key string) (i int) {
+	if v, ok := c.Get(key); ok {
+		i, _ = v.(int)
+	}
+	return
+}
+
+func (c *Context) GetInt64(key string) (i int64) {
+	if v, ok := c.Get(key); ok {
+		i, _ = v.(int64)
+	}
+	return
+}
+
+func (c *Context) GetUint64(key string) (u uint64) {
+	if v, ok := c.Get(key); ok {
+		u, _ = v.(uint64)
+	}
+	return
+}
+
+func (c *Context) GetFloat64(key string) (f float64) {
+	if v, ok := c.Get(key); ok {
+		f, _ = v.(float64)
+	}
+	return
+}
+
+func (c *Context) GetTime(key string) (t time.Time) {
+	if v, ok := c.Get(key); ok {
+		t, _ = v.(time.Time)
+	}
+	return
+}
+
+func (c *Context) GetDuration(key string) (d time.Duration) {
+	if v, ok := c.Get(key); ok {
+		d, _ = v.(time.Duration)
+	}
+	return
+}
+
+func (c *Context) GetStringSlice(key string) (ss []string) {
+	if v, ok := c.Get(key); ok {
+		ss, _ = v.([]string)
+	}
+	return
+}
+
+func (c *Context) GetStringMap(key string) (sm map[string]interface{}) {
+	if v, ok := c.Get(key); ok {
+		sm, _ = v.(map[string]interface{})
+	}
+	return
+}
+
+//Context实现了context.Context,Deadline、Done、Err委托给请求的context,
+//	客户端断开连接或服务器关闭时Done被关闭,可以直接传给数据库或RPC调用
+var _ context.Context = (*Context)(nil)
+
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.Req == nil {
+		return
+	}
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Err()
+}
+
+//Value 对于字符串类型的key先查找 Set 保存的值,其他情况委托给请求的context
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
+	}
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Value(key)
+}
+
+//Copy 返回可以在处理器返回后继续在goroutine中使用的副本
+//	Context会被对象池复用,在goroutine中只能使用副本;副本不能写入响应,也不能调用 Next
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Req:       c.Req,
+		Path:      c.Path,
+		Method:    c.Method,
+		Params:    append(Params(nil), c.Params...),
+		fullPath:  c.fullPath,
+		engine:    c.engine,
+		csrfToken: c.csrfToken,
+		csrfField: c.csrfField,
+		cspNonce:  c.cspNonce,
+		principal: c.principal,
+		index:     abortIndex,
+
+		errorHandled: c.errorHandled,
+	}
+	cp.writermem.status = c.writermem.status
+	cp.writermem.size = c.writermem.size
+	cp.writermem.log = c.writermem.log
+	cp.Writer = &cp.writermem
+	cp.session = c.session.bind(cp)
+	c.mu.RLock()
+	if c.keys != nil {
+		cp.keys = make(map[string]interface{}, len(c.keys))
+		for k, v := range c.keys {
+			cp.keys[k] = v
+		}
+	}
+	c.mu.RUnlock()
+	return cp
+}
+
 //func (c *Context) Redirect(code int, url string) {
 //
 //}
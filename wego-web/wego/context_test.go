@@ -0,0 +1,145 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAbort(t *testing.T) {
+	r := New()
+	after := false
+	r.Use(func(c *Context) {
+		c.Next()
+		after = c.IsAborted()
+	})
+	r.GET("/private", func(c *Context) {
+		c.AbortWithStatusJSON(http.StatusForbidden, H{"message": "denied"})
+	}, func(c *Context) {
+		t.Fatal("handlers after Abort shouldn't be executed")
+	})
+	r.GET("/fail", func(c *Context) {
+		c.Fail(http.StatusBadRequest, "bad")
+	}, func(c *Context) {
+		t.Fatal("handlers after Fail shouldn't be executed")
+	})
+
+	for _, tc := range []struct {
+		path string
+		code int
+	}{{"/private", http.StatusForbidden}, {"/fail", http.StatusBadRequest}} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.code || !after {
+			t.Fatalf("%s: status should be %d and chain aborted, got %d %v", tc.path, tc.code, w.Code, after)
+		}
+	}
+}
+
+func TestResponseWriterStatus(t *testing.T) {
+	r := New()
+	var status, size int
+	r.Use(func(c *Context) {
+		c.Next()
+		status, size = c.Writer.Status(), c.Writer.Size()
+	})
+	r.GET("/raw", func(c *Context) {
+		c.Writer.WriteHeader(http.StatusAccepted)
+		c.Writer.Write([]byte("hello"))
+		//响应头发送后不能再修改状态码
+		c.Status(http.StatusInternalServerError)
+	})
+	r.GET("/empty", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/raw", nil))
+	if w.Code != http.StatusAccepted || status != http.StatusAccepted || size != 5 {
+		t.Fatalf("unexpected status or size: %d %d %d", w.Code, status, size)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/empty", nil))
+	if w.Code != http.StatusNoContent || status != http.StatusNoContent || size != -1 {
+		t.Fatalf("unexpected status or size: %d %d %d", w.Code, status, size)
+	}
+}
+
+func TestContextKeys(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		c.Set("user", "geektutu")
+		c.Set("id", 42)
+		c.Set("roles", []string{"admin"})
+		c.Next()
+	})
+	copies := make(chan *Context, 2)
+	r.GET("/users/:name", func(c *Context) {
+		if c.GetString("user") != "geektutu" || c.GetInt("id") != 42 || c.GetStringSlice("roles")[0] != "admin" {
+			t.Fatal("values set by middleware should be visible to handlers")
+		}
+		if c.GetInt("user") != 0 || c.GetString("missing") != "" {
+			t.Fatal("typed getters should return zero values for wrong types or missing keys")
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				c.Set("n", i)
+				c.GetInt("n")
+			}(i)
+		}
+		wg.Wait()
+		copies <- c.Copy()
+		c.String(http.StatusOK, "ok")
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/tom", nil))
+	//原Context被对象池复用后副本不受影响
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/jerry", nil))
+	<-copies
+	cp := <-copies
+	if cp.Param("name") != "jerry" || cp.FullPath() != "/users/:name" || cp.MustGet("user") != "geektutu" {
+		t.Fatalf("copy lost request data: %v %q", cp.Params, cp.FullPath())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustGet should panic for missing keys")
+		}
+	}()
+	cp.MustGet("missing")
+}
+
+type ctxKey struct{}
+
+func TestContextContext(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		var ctx context.Context = c
+		if ctx.Value(ctxKey{}) != "from request" {
+			t.Fatal("Value should delegate to the request context")
+		}
+		c.Set("key", "from set")
+		if ctx.Value("key") != "from set" {
+			t.Fatal("Value should return values stored with Set")
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Fatal("Deadline should delegate to the request context")
+		}
+		<-ctx.Done()
+		if ctx.Err() != context.DeadlineExceeded {
+			t.Fatalf("unexpected error %v", ctx.Err())
+		}
+		c.Status(http.StatusGatewayTimeout)
+	})
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "from request"), 10*time.Millisecond)
+	defer cancel()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+}
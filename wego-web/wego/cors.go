@@ -0,0 +1,111 @@
+package wego
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//CORSConfig 为 CORS 中间件的设定
+type CORSConfig struct {
+	//AllowOrigins 为允许的来源,支持完全匹配、 * 以及包含一个 * 的通配,如 https://*.example.com
+	AllowOrigins []string
+	//AllowOriginFunc 不为nil时AllowOrigins未匹配的来源交给它判断
+	AllowOriginFunc func(origin string) bool
+	//AllowMethods 为预检请求允许的请求方式,默认为常用的请求方式
+	AllowMethods []string
+	//AllowHeaders 为预检请求允许的请求头,为空时允许请求中的所有请求头
+	AllowHeaders []string
+	//ExposeHeaders 为允许浏览器读取的响应头
+	ExposeHeaders []string
+	//AllowCredentials 允许携带cookie等凭证,不能与 AllowOrigins 中的 * 同时使用
+	AllowCredentials bool
+	//MaxAge 为浏览器缓存预检结果的时间
+	MaxAge time.Duration
+}
+
+//CORS 处理跨域请求,预检请求直接返回204,不需要注册OPTIONS路由
+//	需要在注册路由之前通过 Engine.Use 使用,才能同时处理未匹配到路由的预检请求
+//	AllowOrigins 包含 * 且 AllowCredentials 为true时panic,否则任何网站都可以携带用户的凭证发起请求
+func CORS(config CORSConfig) HandlerFunc {
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+			http.MethodDelete, http.MethodHead, http.MethodOptions}
+	}
+	allowAll := false
+	for _, origin := range config.AllowOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+	}
+	if allowAll && config.AllowCredentials {
+		panic("wego: CORS can't allow all origins with credentials, list the allowed origins instead")
+	}
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge / time.Second))
+
+	return func(c *Context) {
+		origin := c.Req.Header.Get("Origin")
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		preflight := c.Method == http.MethodOptions && c.Req.Header.Get("Access-Control-Request-Method") != ""
+		if !allowAll && !config.allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.Req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func (config *CORSConfig) allowOrigin(origin string) bool {
+	for _, allowed := range config.AllowOrigins {
+		if i := strings.IndexByte(allowed, '*'); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		} else if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin)
+}
@@ -0,0 +1,74 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	r := New()
+	r.Use(CORS(CORSConfig{
+		AllowOrigins:     []string{"https://example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(origin string) bool { return strings.HasSuffix(origin, ".test") },
+		ExposeHeaders:    []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}))
+	r.GET("/users", func(c *Context) {
+		c.String(http.StatusOK, "users")
+	})
+
+	for _, tc := range []struct {
+		method, path, origin string
+		code                 int
+		allowOrigin          string
+	}{
+		{http.MethodGet, "/users", "https://example.com", http.StatusOK, "https://example.com"},
+		{http.MethodGet, "/users", "https://api.example.org", http.StatusOK, "https://api.example.org"},
+		{http.MethodGet, "/users", "http://app.test", http.StatusOK, "http://app.test"},
+		{http.MethodGet, "/users", "https://evil.com", http.StatusOK, ""},
+		//未注册OPTIONS路由以及不存在的路由也能响应预检请求
+		{http.MethodOptions, "/users", "https://example.com", http.StatusNoContent, "https://example.com"},
+		{http.MethodOptions, "/missing", "https://example.com", http.StatusNoContent, "https://example.com"},
+		{http.MethodOptions, "/users", "https://evil.com", http.StatusForbidden, ""},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Origin", tc.origin)
+		if tc.method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			req.Header.Set("Access-Control-Request-Headers", "Content-Type, X-Token")
+		}
+		r.ServeHTTP(w, req)
+		header := w.Header()
+		if w.Code != tc.code || header.Get("Access-Control-Allow-Origin") != tc.allowOrigin {
+			t.Fatalf("%s %s from %s: unexpected response %d %v", tc.method, tc.path, tc.origin, w.Code, header)
+		}
+		if tc.allowOrigin == "" {
+			continue
+		}
+		if header.Get("Access-Control-Allow-Credentials") != "true" {
+			t.Fatalf("credentials should be allowed, got %v", header)
+		}
+		if tc.method == http.MethodOptions && (header.Get("Access-Control-Max-Age") != "3600" ||
+			header.Get("Access-Control-Allow-Headers") != "Content-Type, X-Token" ||
+			!strings.Contains(header.Get("Access-Control-Allow-Methods"), http.MethodPut)) {
+			t.Fatalf("unexpected preflight headers %v", header)
+		}
+		if tc.method == http.MethodGet && header.Get("Access-Control-Expose-Headers") != "X-Total" {
+			t.Fatalf("unexpected expose headers %v", header)
+		}
+	}
+}
+
+func TestCORSAllowAllWithCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("allowing all origins with credentials should panic")
+		}
+	}()
+	CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
@@ -0,0 +1,127 @@
+package wego
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+)
+
+//csrfSessionKey 为同步令牌在会话中的键
+const csrfSessionKey = "_csrf"
+
+//CSRF令牌的保存方式
+const (
+	//CSRFDoubleSubmit 令牌保存在cookie中,请求时需要通过请求头或表单再提交一次
+	CSRFDoubleSubmit = iota
+	//CSRFSynchronizer 令牌保存在会话中,需要先使用 Sessions 中间件
+	CSRFSynchronizer
+)
+
+//CSRFConfig 为 CSRF 中间件的设定,零值字段使用默认值
+type CSRFConfig struct {
+	Mode       int    //令牌的保存方式,默认为 CSRFDoubleSubmit
+	HeaderName string //提交令牌的请求头,默认为 X-CSRF-Token
+	FormField  string //提交令牌的表单字段,默认为 csrf_token
+
+	//以下设定只用于 CSRFDoubleSubmit 模式下保存令牌的cookie
+	//	该cookie不使用HttpOnly,单页应用可以在javascript中读取后通过请求头提交
+	CookieName string //默认为 wego_csrf
+	Path       string //默认为 /
+	Domain     string
+	Secure     bool
+	SameSite   http.SameSite //默认为 http.SameSiteStrictMode
+}
+
+//CSRF 防止跨站请求伪造,GET、HEAD、OPTIONS、TRACE以外的请求必须提交正确的令牌,否则返回403
+//	处理器中使用 c.CSRFToken() 获取令牌,模板中可以使用 c.CSRFField() 生成隐藏的表单字段
+func CSRF(config CSRFConfig) HandlerFunc {
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.FormField == "" {
+		config.FormField = "csrf_token"
+	}
+	if config.CookieName == "" {
+		config.CookieName = "wego_csrf"
+	}
+	if config.Path == "" {
+		config.Path = "/"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteStrictMode
+	}
+	return func(c *Context) {
+		token, err := config.loadToken(c)
+		if err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.csrfToken, c.csrfField = token, config.FormField
+
+		switch c.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			c.Next()
+			return
+		}
+		submitted := c.Req.Header.Get(config.HeaderName)
+		if submitted == "" {
+			submitted = c.PostForm(config.FormField)
+		}
+		if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			c.Fail(http.StatusForbidden, "CSRF token mismatch")
+			return
+		}
+		c.Next()
+	}
+}
+
+//loadToken 读取已有的令牌,不存在时生成新的令牌并保存
+func (config *CSRFConfig) loadToken(c *Context) (string, error) {
+	if config.Mode == CSRFSynchronizer {
+		s := c.Session()
+		if token, ok := s.Get(csrfSessionKey).(string); ok && token != "" {
+			return token, nil
+		}
+		token := newCSRFToken()
+		s.Set(csrfSessionKey, token)
+		return token, s.Save()
+	}
+	if cookie, err := c.Cookie(config.CookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	token := newCSRFToken()
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     config.CookieName,
+		Value:    token,
+		Path:     config.Path,
+		Domain:   config.Domain,
+		Secure:   config.Secure,
+		SameSite: config.SameSite,
+	})
+	return token, nil
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+//CSRFToken 返回 CSRF 中间件生成的令牌,未使用该中间件时返回空字符串
+func (c *Context) CSRFToken() string {
+	return c.csrfToken
+}
+
+//CSRFField 返回包含令牌的隐藏表单字段,可以直接传给模板输出
+//	c.HTMLTemplate(http.StatusOK, "form.tmpl", wego.H{"csrfField": c.CSRFField()})
+func (c *Context) CSRFField() template.HTML {
+	if c.csrfToken == "" {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(c.csrfField) +
+		`" value="` + template.HTMLEscapeString(c.csrfToken) + `">`)
+}
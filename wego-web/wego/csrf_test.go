@@ -0,0 +1,55 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	for _, mode := range []int{CSRFDoubleSubmit, CSRFSynchronizer} {
+		r := New()
+		if mode == CSRFSynchronizer {
+			r.Use(Sessions(NewMemoryStore(), SessionOptions{}))
+		}
+		r.Use(CSRF(CSRFConfig{Mode: mode}))
+		r.GET("/form", func(c *Context) {
+			c.HTML(http.StatusOK, string(c.CSRFField()))
+		})
+		r.POST("/form", func(c *Context) {
+			c.String(http.StatusOK, "ok")
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+		cookies := w.Result().Cookies()
+		token := strings.TrimSuffix(strings.TrimPrefix(w.Body.String(), `<input type="hidden" name="csrf_token" value="`), `">`)
+		if len(cookies) != 1 || token == "" || token == w.Body.String() {
+			t.Fatalf("mode %d: unexpected form %q %v", mode, w.Body.String(), cookies)
+		}
+		if mode == CSRFDoubleSubmit && cookies[0].HttpOnly {
+			t.Fatal("double submit cookie should be readable by javascript")
+		}
+
+		post := func(token string, header bool) int {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(url.Values{"csrf_token": {token}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if header {
+				req = httptest.NewRequest(http.MethodPost, "/form", nil)
+				req.Header.Set("X-CSRF-Token", token)
+			}
+			req.AddCookie(cookies[0])
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+		if post(token, false) != http.StatusOK || post(token, true) != http.StatusOK {
+			t.Fatalf("mode %d: request with the token should be accepted", mode)
+		}
+		if post("", false) != http.StatusForbidden || post(token+"x", true) != http.StatusForbidden {
+			t.Fatalf("mode %d: request without a valid token should be rejected", mode)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package wego
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+//MIMEProblemJSON 为RFC 7807问题详情的MIME类型
+const MIMEProblemJSON = "application/problem+json"
+
+//Problem 为RFC 7807定义的问题详情,同时实现了error,可以通过 c.Error 记录
+type Problem struct {
+	Type     string //问题类型的URI,默认为 about:blank
+	Title    string //简短的描述,默认为状态码对应的文本
+	Status   int
+	Detail   string //本次问题的详细说明
+	Instance string //发生问题的URI
+	//Extensions 为其他的成员,与以上字段一起编码在json中
+	Extensions map[string]interface{}
+	//Err 为导致问题的原始错误,不会出现在响应中
+	Err error
+}
+
+//NewProblem 创建状态码为status的Problem
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Status: status, Detail: detail}
+}
+
+//Wrap 返回以err为原始错误的Problem
+func (p *Problem) Wrap(err error) *Problem {
+	p.Err = err
+	return p
+}
+
+//With 添加扩展成员
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) Error() string {
+	msg := p.Detail
+	if msg == "" {
+		msg = p.title()
+	}
+	if p.Err != nil {
+		msg += ": " + p.Err.Error()
+	}
+	return msg
+}
+
+func (p *Problem) Unwrap() error {
+	return p.Err
+}
+
+func (p *Problem) title() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return http.StatusText(p.Status)
+}
+
+//MarshalJSON 将扩展成员与标准成员编码在同一个对象中,标准成员优先
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	members["type"] = "about:blank"
+	if p.Type != "" {
+		members["type"] = p.Type
+	}
+	members["title"] = p.title()
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	} else {
+		delete(members, "detail")
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	} else {
+		delete(members, "instance")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(members); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+//Error 记录处理请求时发生的错误,由 ErrorHandler 统一返回错误响应,并记录在访问日志中
+//	err为nil时不做任何事
+func (c *Context) Error(err error) {
+	if err != nil {
+		c.errs = append(c.errs, err)
+	}
+}
+
+//AbortWithError 记录错误并中断处理链
+func (c *Context) AbortWithError(err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+//Errors 返回 Error 记录的所有错误
+func (c *Context) Errors() []error {
+	return c.errs
+}
+
+//ErrorHandlerConfig 为 ErrorHandler 的设定
+type ErrorHandlerConfig struct {
+	//Statuses 将错误映射为状态码,使用errors.Is比较,如 sql.ErrNoRows: 404
+	Statuses map[error]int
+	//Map 将错误转换为Problem,返回nil时使用默认的转换
+	Map func(err error) *Problem
+	//Render 不为空时用于返回错误响应,默认返回 application/problem+json
+	Render func(c *Context, p *Problem)
+}
+
+//ErrorHandler 在之后的处理器记录了错误且尚未写入响应时,使用最后一个错误返回统一的错误响应
+//	*Problem 直接使用,ValidationErrors与请求体的解析错误返回400, ErrBodyTooLarge 返回413,
+//	超时返回503,其他错误以及Status为0的Problem返回500; ReleaseMode 下状态码不小于500的响应不包含错误的详细信息
+//	Trace 中间件设定了请求ID时,响应中包含 request_id
+func ErrorHandler(config ErrorHandlerConfig) HandlerFunc {
+	if config.Render == nil {
+		config.Render = func(c *Context, p *Problem) {
+			c.SetHeader("Content-Type", MIMEProblemJSON)
+			c.JSON(p.Status, p)
+		}
+	}
+	return func(c *Context) {
+		outer := c.errorHandled
+		c.errorHandled = true
+		defer func() {
+			//panic时同样还原,使外层的 Recovery 自行返回错误响应
+			c.errorHandled = outer
+		}()
+		c.Next()
+		if len(c.errs) == 0 || c.Writer.Written() {
+			return
+		}
+		p := config.problem(c.errs[len(c.errs)-1])
+		if p.Status >= 500 && Mode() == ReleaseMode {
+			//不向客户端暴露内部错误
+			p = &Problem{Status: p.Status, Type: p.Type, Title: p.Title}
+		}
+		if id := c.RequestID(); id != "" {
+			p.With("request_id", id)
+		}
+		config.Render(c, p)
+	}
+}
+
+//clone 返回可以修改的副本,Status为0时使用500
+func (p *Problem) clone() *Problem {
+	cp := *p
+	if cp.Status == 0 {
+		cp.Status = http.StatusInternalServerError
+	}
+	cp.Extensions = make(map[string]interface{}, len(p.Extensions))
+	for k, v := range p.Extensions {
+		cp.Extensions[k] = v
+	}
+	return &cp
+}
+
+//problem 将err转换为Problem,返回的Problem可以修改
+func (config *ErrorHandlerConfig) problem(err error) *Problem {
+	if config.Map != nil {
+		if p := config.Map(err); p != nil {
+			return p.clone()
+		}
+	}
+	var p *Problem
+	if errors.As(err, &p) {
+		return p.clone()
+	}
+	for target, status := range config.Statuses {
+		if errors.Is(err, target) {
+			return &Problem{Status: status, Detail: err.Error(), Err: err}
+		}
+	}
+	var validation ValidationErrors
+	var syntax *json.SyntaxError
+	var unmarshalType *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validation):
+		return &Problem{Status: http.StatusBadRequest, Detail: "request validation failed", Err: err, Extensions: map[string]interface{}{"errors": validation}}
+	case errors.As(err, &syntax), errors.As(err, &unmarshalType):
+		return &Problem{Status: http.StatusBadRequest, Detail: err.Error(), Err: err}
+	case errors.Is(err, ErrBodyTooLarge):
+		return &Problem{Status: http.StatusRequestEntityTooLarge, Detail: err.Error(), Err: err}
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return &Problem{Status: http.StatusServiceUnavailable, Detail: err.Error(), Err: err}
+	}
+	return &Problem{Status: http.StatusInternalServerError, Detail: err.Error(), Err: err}
+}
@@ -0,0 +1,163 @@
+package wego
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var errNotFound = errors.New("record not found")
+
+func TestErrorHandler(t *testing.T) {
+	r := New()
+	r.Use(Trace(TraceConfig{}))
+	r.Use(ErrorHandler(ErrorHandlerConfig{Statuses: map[error]int{errNotFound: http.StatusNotFound}}))
+	r.GET("/problem", func(c *Context) {
+		c.Error(NewProblem(http.StatusConflict, "name already taken").With("name", "tom"))
+	})
+	r.GET("/mapped", func(c *Context) {
+		c.Error(errors.New("first"))
+		c.AbortWithError(errNotFound)
+	}, func(c *Context) {
+		t.Fatal("handlers after AbortWithError shouldn't be executed")
+	})
+	r.GET("/validation", func(c *Context) {
+		var obj struct {
+			Name string `binding:"required"`
+		}
+		c.Error(Validate(&obj))
+	})
+	r.GET("/internal", func(c *Context) {
+		c.Error(errors.New("db password is wrong"))
+	})
+	r.GET("/written", func(c *Context) {
+		c.Error(errors.New("ignored"))
+		c.String(http.StatusOK, "ok")
+	})
+
+	get := func(path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w, body
+	}
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		detail string
+	}{
+		{"/problem", http.StatusConflict, "name already taken"},
+		{"/mapped", http.StatusNotFound, "record not found"},
+		{"/validation", http.StatusBadRequest, "request validation failed"},
+		{"/internal", http.StatusInternalServerError, "db password is wrong"},
+	} {
+		w, body := get(tc.path)
+		if w.Code != tc.status || w.Header().Get("Content-Type") != MIMEProblemJSON {
+			t.Fatalf("%s: unexpected response %d %q", tc.path, w.Code, w.Header().Get("Content-Type"))
+		}
+		if body["status"] != float64(tc.status) || body["title"] != http.StatusText(tc.status) || body["type"] != "about:blank" ||
+			body["detail"] != tc.detail || body["request_id"] != w.Header().Get(HeaderRequestID) {
+			t.Fatalf("%s: unexpected problem %v", tc.path, body)
+		}
+	}
+	if _, body := get("/problem"); body["name"] != "tom" {
+		t.Fatalf("extension members should be encoded, got %v", body)
+	}
+	if _, body := get("/validation"); len(body["errors"].([]interface{})) != 1 {
+		t.Fatalf("validation errors should be encoded, got %v", body)
+	}
+	if w, _ := get("/written"); w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatal("responses already written should not be replaced")
+	}
+
+	SetMode(ReleaseMode)
+	defer SetMode(DebugMode)
+	if _, body := get("/internal"); body["detail"] != nil || body["status"] != float64(500) {
+		t.Fatalf("internal errors should be hidden in release mode, got %v", body)
+	}
+	if _, body := get("/problem"); body["detail"] != "name already taken" {
+		t.Fatalf("client errors should be kept in release mode, got %v", body)
+	}
+}
+
+func TestNoRouteNoMethod(t *testing.T) {
+	r := New()
+	calls := 0
+	r.Use(func(c *Context) {
+		calls++
+		c.Next()
+	})
+	r.Use(ErrorHandler(ErrorHandlerConfig{}))
+	r.NoRoute(func(c *Context) {
+		c.Error(NewProblem(c.Writer.Status(), "no route for "+c.Path))
+	})
+	r.NoMethod(func(c *Context) {
+		c.JSON(c.Writer.Status(), H{"allow": c.Writer.Header().Get("Allow")})
+	})
+	r.GET("/users", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != MIMEProblemJSON {
+		t.Fatalf("unexpected 404 response %d %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "{\"allow\":\"GET, HEAD, OPTIONS\"}\n" {
+		t.Fatalf("unexpected 405 response %d %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/users", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("OPTIONS should still be answered automatically, got %d", w.Code)
+	}
+	if calls != 3 {
+		t.Fatalf("global middlewares should run for every request, got %d", calls)
+	}
+}
+
+func TestErrorHandlerRecovery(t *testing.T) {
+	mapped := &Problem{Detail: "mapped without status"}
+	inner := New()
+	inner.Use(ErrorHandler(ErrorHandlerConfig{Map: func(err error) *Problem {
+		if errors.Is(err, errNotFound) {
+			return mapped
+		}
+		return nil
+	}}))
+	inner.Use(Recovery())
+	outer := New()
+	outer.Use(Recovery())
+	outer.Use(ErrorHandler(ErrorHandlerConfig{}))
+	for _, r := range []*Engine{inner, outer} {
+		r.GET("/panic", func(c *Context) {
+			panic("db password is wrong")
+		})
+		r.GET("/mapped", func(c *Context) {
+			c.Error(errNotFound)
+		})
+	}
+
+	//ErrorHandler在Recovery之外时由其返回panic的错误响应
+	w := httptest.NewRecorder()
+	inner.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != MIMEProblemJSON || strings.Contains(w.Body.String(), "password") {
+		t.Fatalf("panics should be rendered by the outer ErrorHandler, got %d %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	inner.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mapped", nil))
+	if w.Code != http.StatusInternalServerError || mapped.Status != 0 {
+		t.Fatalf("problems mapped without a status should use 500, got %d", w.Code)
+	}
+	//ErrorHandler在Recovery之内时由Recovery返回错误响应
+	w = httptest.NewRecorder()
+	outer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "{\"message\":\"Internal Server Error\"}\n" {
+		t.Fatalf("Recovery should respond when no ErrorHandler is outside, got %d %q", w.Code, w.Body.String())
+	}
+}
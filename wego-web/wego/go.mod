@@ -1,3 +1,5 @@
 module wego
 
 go 1.17
+
+require google.golang.org/protobuf v1.27.1
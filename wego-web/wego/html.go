@@ -0,0 +1,273 @@
+package wego
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+//HTMLRender 为可替换的html模板引擎,通过 Engine.HTMLRender 设定后由 Context.HTMLTemplate 使用
+type HTMLRender interface {
+	//Instance 返回使用名为name的模板渲染data的Render
+	Instance(name string, data interface{}) Render
+}
+
+//HTMLTemplateRender 使用html/template渲染数据,先渲染到缓冲区,成功后才写入响应
+type HTMLTemplateRender struct {
+	Template *template.Template
+	Name     string //为空时执行Template本身
+	Data     interface{}
+}
+
+func (r HTMLTemplateRender) Render(w http.ResponseWriter) error {
+	var buf bytes.Buffer
+	var err error
+	if r.Name == "" {
+		err = r.Template.Execute(&buf, r.Data)
+	} else {
+		err = r.Template.ExecuteTemplate(&buf, r.Name, r.Data)
+	}
+	if err != nil {
+		return err
+	}
+	return writeRendered(w, r, buf.Bytes())
+}
+
+func (r HTMLTemplateRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEHTML+"; charset=utf-8")
+}
+
+//errorRender 在找不到模板时返回错误
+type errorRender struct {
+	err error
+}
+
+func (r errorRender) Render(http.ResponseWriter) error     { return r.err }
+func (r errorRender) WriteContentType(http.ResponseWriter) {}
+
+//TemplateSet 描述一组共享布局的页面,每个页面与所有的布局一起解析为一个单独的模板集
+//	布局中使用 {{block "content" .}}{{end}} 定义可替换的部分,页面中使用 {{define "content"}}...{{end}} 替换
+type TemplateSet struct {
+	//FS 为模板文件所在的文件系统,如embed.FS,为nil时读取磁盘上的文件
+	FS fs.FS
+	//Layouts 为布局与公共部分的glob模式,如 layouts/*.html 、 partials/*.html ,按顺序解析
+	Layouts []string
+	//Pages 为页面的glob模式,每个页面使用其路径作为名称,如 pages/users/index.html
+	Pages []string
+	//Layout 为渲染页面时执行的模板名称,如 base.html ,为空时执行页面本身
+	Layout string
+}
+
+//HTMLTemplates 为基于html/template的 HTMLRender ,支持多个模板集、布局与 DebugMode 下的热加载
+//	DebugMode 下每次渲染前检查模板文件,文件被修改、增加或删除时重新解析
+type HTMLTemplates struct {
+	mu    sync.RWMutex
+	funcs template.FuncMap
+	sets  map[string]*templateEntry
+	globs []*templateEntry //LoadGlob 加载的模板,按模板名称查找
+}
+
+//templateEntry 为一个模板集,以及重新解析所需的信息
+type templateEntry struct {
+	fsys     fs.FS
+	patterns []string //按顺序解析的glob模式或文件
+	execute  string   //执行的模板名称,为空时按 Instance 的name查找
+	tmpl     *template.Template
+	stamp    string //所有文件的路径与修改时间
+}
+
+//NewHTMLTemplates 创建HTMLTemplates,funcs为模板中可以使用的函数
+func NewHTMLTemplates(funcs template.FuncMap) *HTMLTemplates {
+	return &HTMLTemplates{funcs: funcs, sets: make(map[string]*templateEntry)}
+}
+
+//Load 加载TemplateSet中的所有页面,页面名称相同时覆盖已有的模板集
+func (t *HTMLTemplates) Load(set TemplateSet) error {
+	pages, err := globFiles(set.FS, set.Pages)
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		return fmt.Errorf("wego: no pages match %v", set.Pages)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, page := range pages {
+		e := &templateEntry{fsys: set.FS, patterns: append(append([]string(nil), set.Layouts...), page), execute: set.Layout}
+		if e.execute == "" {
+			e.execute = filepath.Base(page)
+		}
+		if err := e.parse(t.funcs); err != nil {
+			return err
+		}
+		t.sets[page] = e
+	}
+	return nil
+}
+
+//LoadGlob 解析所有匹配的模板文件,渲染时按模板名称(默认为文件名)查找
+//	fsys为nil时读取磁盘上的文件
+func (t *HTMLTemplates) LoadGlob(fsys fs.FS, patterns ...string) error {
+	e := &templateEntry{fsys: fsys, patterns: patterns}
+	if err := e.parse(t.funcs); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.globs = append(t.globs, e)
+	t.mu.Unlock()
+	return nil
+}
+
+//Add 添加使用其他方式创建的模板集,渲染时执行tmpl本身
+func (t *HTMLTemplates) Add(name string, tmpl *template.Template) {
+	t.mu.Lock()
+	t.sets[name] = &templateEntry{tmpl: tmpl}
+	t.mu.Unlock()
+}
+
+//SetFuncs 替换模板中可以使用的函数,并重新解析所有从文件加载的模板
+func (t *HTMLTemplates) SetFuncs(funcs template.FuncMap) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.funcs = funcs
+	for _, e := range t.entries() {
+		if err := e.parse(funcs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *HTMLTemplates) entries() []*templateEntry {
+	entries := append([]*templateEntry(nil), t.globs...)
+	for _, e := range t.sets {
+		if e.patterns != nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+//Instance 优先使用名为name的模板集,否则在 LoadGlob 加载的模板中查找名为name的模板
+func (t *HTMLTemplates) Instance(name string, data interface{}) Render {
+	if Mode() == DebugMode {
+		if err := t.reload(); err != nil {
+			return errorRender{err}
+		}
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if e, ok := t.sets[name]; ok {
+		return HTMLTemplateRender{Template: e.tmpl, Name: e.execute, Data: data}
+	}
+	for i := len(t.globs) - 1; i >= 0; i-- {
+		if tmpl := t.globs[i].tmpl.Lookup(name); tmpl != nil {
+			return HTMLTemplateRender{Template: tmpl, Data: data}
+		}
+	}
+	return errorRender{fmt.Errorf("wego: html template %q is not defined", name)}
+}
+
+//reload 重新解析文件有变化的模板集
+func (t *HTMLTemplates) reload() error {
+	t.mu.RLock()
+	var changed []*templateEntry
+	for _, e := range t.entries() {
+		if stamp, err := e.fileStamp(); err != nil || stamp != e.stamp {
+			changed = append(changed, e)
+		}
+	}
+	t.mu.RUnlock()
+	if len(changed) == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range changed {
+		if err := e.parse(t.funcs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//parse 按顺序解析所有文件,需要持有写锁或尚未被其他goroutine使用
+func (e *templateEntry) parse(funcs template.FuncMap) error {
+	files, err := globFiles(e.fsys, e.patterns)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("wego: no templates match %v", e.patterns)
+	}
+	stamp, err := e.fileStamp()
+	if err != nil {
+		return err
+	}
+	tmpl := template.New(filepath.Base(files[0])).Funcs(funcs)
+	if e.fsys == nil {
+		tmpl, err = tmpl.ParseFiles(files...)
+	} else {
+		tmpl, err = tmpl.ParseFS(e.fsys, files...)
+	}
+	if err != nil {
+		return err
+	}
+	e.tmpl, e.stamp = tmpl, stamp
+	return nil
+}
+
+//fileStamp 返回所有匹配的文件及其修改时间,用于判断模板是否需要重新解析
+func (e *templateEntry) fileStamp() (string, error) {
+	files, err := globFiles(e.fsys, e.patterns)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	for _, file := range files {
+		var info fs.FileInfo
+		if e.fsys == nil {
+			info, err = os.Stat(file)
+		} else {
+			info, err = fs.Stat(e.fsys, file)
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, "%s %d %s\n", file, info.Size(), info.ModTime().Format(time.RFC3339Nano))
+	}
+	return buf.String(), nil
+}
+
+//globFiles 返回按顺序匹配各个模式的文件,每个模式匹配到的文件按名称排序
+func globFiles(fsys fs.FS, patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		var matches []string
+		var err error
+		if fsys == nil {
+			matches, err = filepath.Glob(pattern)
+		} else {
+			matches, err = fs.Glob(fsys, pattern)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	return files, nil
+}
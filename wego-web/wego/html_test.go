@@ -0,0 +1,171 @@
+package wego
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func writeTemplate(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func renderHTML(r *Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestHTMLTemplateSets(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, filepath.Join(dir, "layouts/base.html"), `<title>{{block "title" .}}wego{{end}}</title>{{template "nav" .}}<main>{{block "content" .}}{{end}}</main>`)
+	writeTemplate(t, filepath.Join(dir, "partials/nav.html"), `{{define "nav"}}<nav>{{upper .Name}}</nav>{{end}}`)
+	writeTemplate(t, filepath.Join(dir, "pages/index.html"), `{{define "content"}}index {{.Name}}{{end}}`)
+	writeTemplate(t, filepath.Join(dir, "pages/about.html"), `{{define "title"}}about{{end}}{{define "content"}}about {{.Name}}{{end}}`)
+
+	r := New()
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	r.LoadHTMLTemplates(TemplateSet{
+		Layouts: []string{filepath.Join(dir, "layouts/*.html"), filepath.Join(dir, "partials/*.html")},
+		Pages:   []string{filepath.Join(dir, "pages/*.html")},
+		Layout:  "base.html",
+	})
+	r.GET("/:page", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, filepath.Join(dir, "pages", c.Param("page")+".html"), H{"Name": "<wego>"})
+	})
+
+	w := renderHTML(r, "/index")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := w.Body.String(); body != "<title>wego</title><nav>&lt;WEGO&gt;</nav><main>index &lt;wego&gt;</main>" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	//每个页面使用单独的模板集,content不会互相覆盖
+	if body := renderHTML(r, "/about").Body.String(); body != "<title>about</title><nav>&lt;WEGO&gt;</nav><main>about &lt;wego&gt;</main>" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	w = renderHTML(r, "/missing")
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "<") {
+		t.Fatalf("missing template should return 500, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestHTMLTemplateGlobAndFS(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, filepath.Join(dir, "hello.tmpl"), `hello {{.}}{{if suffix}}{{suffix}}{{end}}`)
+
+	r := New()
+	r.SetFuncMap(template.FuncMap{"suffix": func() string { return "" }})
+	r.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	r.LoadHTMLFS(fstest.MapFS{
+		"views/user.tmpl": {Data: []byte(`{{define "user"}}user {{.}}{{end}}`)},
+	}, "views/*.tmpl")
+	//在加载模板之后设定的函数同样生效
+	r.SetFuncMap(template.FuncMap{"suffix": func() string { return "!" }})
+	r.GET("/hello", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, "hello.tmpl", "wego")
+	})
+	r.GET("/user", func(c *Context) {
+		c.HTMLTemplate(http.StatusCreated, "user", "tom")
+	})
+
+	if w := renderHTML(r, "/hello"); w.Code != http.StatusOK || w.Body.String() != "hello wego!" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if w := renderHTML(r, "/user"); w.Code != http.StatusCreated || w.Body.String() != "user tom" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestHTMLTemplateRenderError(t *testing.T) {
+	r := New()
+	r.GET("/none", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, "index.html", nil)
+	})
+	if w := renderHTML(r, "/none"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("rendering without templates should return 500, got %d", w.Code)
+	}
+
+	r.LoadHTMLFS(fstest.MapFS{
+		"broken.html": {Data: []byte(`<p>before</p>{{.Missing.Field}}<p>after</p>`)},
+	}, "*.html")
+	r.GET("/broken", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, "broken.html", H{"Missing": 1})
+	})
+	w := renderHTML(r, "/broken")
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "before") {
+		t.Fatalf("failed render shouldn't write partial output, got %d %q", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), MIMEJSON) {
+		t.Fatalf("unexpected Content-Type %q", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestHTMLTemplateReload(t *testing.T) {
+	defer SetMode(Mode())
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	writeTemplate(t, page, `v1`)
+
+	r := New()
+	r.LoadHTMLGlob(filepath.Join(dir, "*.html"))
+	r.GET("/", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, "page.html", nil)
+	})
+	modify := func(content string, mtime time.Time) {
+		writeTemplate(t, page, content)
+		if err := os.Chtimes(page, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetMode(ReleaseMode)
+	modify("v2", time.Now().Add(time.Hour))
+	if body := renderHTML(r, "/").Body.String(); body != "v1" {
+		t.Fatalf("templates shouldn't be reloaded in release mode, got %q", body)
+	}
+
+	SetMode(DebugMode)
+	if body := renderHTML(r, "/").Body.String(); body != "v2" {
+		t.Fatalf("templates should be reloaded in debug mode, got %q", body)
+	}
+	modify("{{", time.Now().Add(2*time.Hour))
+	if w := renderHTML(r, "/"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("parse error should return 500, got %d", w.Code)
+	}
+	writeTemplate(t, filepath.Join(dir, "new.html"), `new`)
+	modify("v3", time.Now().Add(3*time.Hour))
+	if body := renderHTML(r, "/").Body.String(); body != "v3" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+type stubHTMLRender struct{}
+
+func (stubHTMLRender) Instance(name string, data interface{}) Render {
+	return HTMLTemplateRender{Template: template.Must(template.New(name).Parse(`stub {{.}}`)), Data: data}
+}
+
+func TestCustomHTMLRender(t *testing.T) {
+	r := New()
+	r.HTMLRender = stubHTMLRender{}
+	r.GET("/", func(c *Context) {
+		c.HTMLTemplate(http.StatusOK, "any", "wego")
+	})
+	if body := renderHTML(r, "/").Body.String(); body != "stub wego" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
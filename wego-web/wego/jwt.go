@@ -0,0 +1,319 @@
+package wego
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//支持的JWT签名算法
+const (
+	JWTHS256 = "HS256"
+	JWTRS256 = "RS256"
+	JWTES256 = "ES256"
+)
+
+//JWTConfig 为 JWT 中间件的设定
+type JWTConfig struct {
+	//Keys 为kid到验证密钥的映射,HS256使用[]byte,RS256使用*rsa.PublicKey,ES256使用P-256的*ecdsa.PublicKey
+	//	令牌没有kid且只有一个密钥时使用该密钥,轮换密钥时同时保留新旧密钥即可
+	Keys map[string]interface{}
+	//KeyFunc 在Keys中找不到kid时调用,可以用于从远程的JWKS中加载新的密钥
+	KeyFunc func(kid string) (interface{}, error)
+	//Algorithms 为允许的签名算法,默认为HS256、RS256与ES256,算法必须与密钥的类型相符
+	Algorithms []string
+	Issuer     string        //不为空时要求iss相同
+	Audience   string        //不为空时要求aud包含该值
+	Leeway     time.Duration //校验exp与nbf时允许的时钟误差
+	//TokenQuery 不为空时也从该查询参数中读取令牌,默认只读取 Authorization: Bearer 请求头
+	TokenQuery string
+}
+
+//JWT 校验 Authorization: Bearer 中的JWT,成功时设定Principal:
+//	Subject为sub,Scopes来自以空格分隔的scope或数组scp,Roles来自数组roles
+//	令牌必须包含exp,校验失败时返回401
+func JWT(config JWTConfig) HandlerFunc {
+	return func(c *Context) {
+		token := ""
+		if auth := c.Req.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			token = strings.TrimSpace(auth[7:])
+		} else if config.TokenQuery != "" {
+			token = c.Query(config.TokenQuery)
+		}
+		if token == "" {
+			c.SetHeader("WWW-Authenticate", "Bearer")
+			c.Fail(http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		claims, err := config.Parse(token)
+		if err != nil {
+			c.SetHeader("WWW-Authenticate", `Bearer error="invalid_token", error_description=`+strconv.Quote(err.Error()))
+			c.Fail(http.StatusUnauthorized, err.Error())
+			return
+		}
+		p := &Principal{Method: "jwt", Claims: claims}
+		p.Subject, _ = claims["sub"].(string)
+		if scope, ok := claims["scope"].(string); ok {
+			p.Scopes = strings.Fields(scope)
+		} else {
+			p.Scopes = stringsClaim(claims["scp"])
+		}
+		p.Roles = stringsClaim(claims["roles"])
+		c.SetPrincipal(p)
+		c.Next()
+	}
+}
+
+//stringsClaim 将字符串数组类型的声明转为[]string
+func stringsClaim(v interface{}) []string {
+	values, _ := v.([]interface{})
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+//Parse 校验令牌的签名与声明,返回所有的声明
+func (config *JWTConfig) Parse(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	algorithms := config.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{JWTHS256, JWTRS256, JWTES256}
+	}
+	if !contains(algorithms, header.Alg) {
+		return nil, fmt.Errorf("algorithm %q is not allowed", header.Alg)
+	}
+	key, err := config.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	if err := verifyJWT(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("token has no expiration")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(config.Leeway)) {
+		return nil, errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if config.Issuer != "" && claims["iss"] != config.Issuer {
+		return nil, errors.New("invalid issuer")
+	}
+	if config.Audience != "" {
+		aud, ok := claims["aud"].(string)
+		if !(ok && aud == config.Audience) && !contains(stringsClaim(claims["aud"]), config.Audience) {
+			return nil, errors.New("invalid audience")
+		}
+	}
+	return claims, nil
+}
+
+//key 返回kid对应的密钥
+func (config *JWTConfig) key(kid string) (interface{}, error) {
+	if key, ok := config.Keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(config.Keys) == 1 {
+		for _, key := range config.Keys {
+			return key, nil
+		}
+	}
+	if config.KeyFunc != nil {
+		return config.KeyFunc(kid)
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+//verifyJWT 使用key验证签名,密钥类型与算法不符时返回错误,防止算法混淆攻击
+func verifyJWT(alg string, key interface{}, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	invalid := errors.New("invalid token signature")
+	switch alg {
+	case JWTHS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New("HS256 requires a []byte key")
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return invalid
+		}
+	case JWTRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 requires an *rsa.PublicKey")
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return invalid
+		}
+	case JWTES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return errors.New("ES256 requires a P-256 *ecdsa.PublicKey")
+		}
+		if len(signature) != 64 {
+			return invalid
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return invalid
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	return nil
+}
+
+//SignJWT 使用key签发令牌,HS256使用[]byte,RS256使用*rsa.PrivateKey,ES256使用P-256的*ecdsa.PrivateKey
+func SignJWT(alg string, kid string, key interface{}, claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: alg, Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	var signature []byte
+	switch alg {
+	case JWTHS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return "", errors.New("wego: HS256 requires a []byte key")
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case JWTRS256:
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return "", errors.New("wego: RS256 requires an *rsa.PrivateKey")
+		}
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:]); err != nil {
+			return "", err
+		}
+	case JWTES256:
+		priv, ok := key.(*ecdsa.PrivateKey)
+		if !ok || priv.Curve != elliptic.P256() {
+			return "", errors.New("wego: ES256 requires a P-256 *ecdsa.PrivateKey")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		if err != nil {
+			return "", err
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	default:
+		return "", fmt.Errorf("wego: unsupported algorithm %q", alg)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+//ParseJWKS 解析JSON Web Key Set,返回可以用作 JWTConfig.Keys 的kid到公钥的映射
+//	支持RSA、P-256的EC以及oct类型的密钥,其他类型的密钥被忽略
+func ParseJWKS(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	decode := func(s string) *big.Int {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil
+		}
+		return new(big.Int).SetBytes(b)
+	}
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, e := decode(k.N), decode(k.E)
+			if n == nil || e == nil || !e.IsInt64() {
+				return nil, fmt.Errorf("wego: invalid RSA key %q", k.Kid)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			x, y := decode(k.X), decode(k.Y)
+			if k.Crv != "P-256" {
+				continue
+			}
+			if x == nil || y == nil || !elliptic.P256().IsOnCurve(x, y) {
+				return nil, fmt.Errorf("wego: invalid EC key %q", k.Kid)
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return nil, fmt.Errorf("wego: invalid oct key %q", k.Kid)
+			}
+			keys[k.Kid] = secret
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,165 @@
+package wego
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("secret")
+	config := JWTConfig{
+		Keys: map[string]interface{}{
+			"hs":  secret,
+			"rs":  &rsaKey.PublicKey,
+			"es":  &ecKey.PublicKey,
+			"old": []byte("old secret"),
+		},
+		Issuer:   "https://issuer.example",
+		Audience: "wego",
+		Leeway:   30 * time.Second,
+	}
+	r := New()
+	r.Use(JWT(config))
+	r.GET("/", RequireScopes("read"), RequireRoles("admin"), func(c *Context) {
+		c.String(http.StatusOK, c.Principal().Subject)
+	})
+
+	now := time.Now().Unix()
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":   "alice",
+			"iss":   "https://issuer.example",
+			"aud":   []string{"other", "wego"},
+			"exp":   now + 60,
+			"scope": "read write",
+			"roles": []string{"admin"},
+		}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	sign := func(alg, kid string, key interface{}, claims map[string]interface{}) string {
+		token, err := SignJWT(alg, kid, key, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`)) + "."
+	//使用RSA公钥作为HMAC密钥伪造的令牌
+	confused := sign(JWTHS256, "rs", rsaKey.PublicKey.N.Bytes(), claims(nil))
+
+	for i, tc := range []struct {
+		token string
+		code  int
+	}{
+		{sign(JWTHS256, "hs", secret, claims(nil)), http.StatusOK},
+		{sign(JWTRS256, "rs", rsaKey, claims(nil)), http.StatusOK},
+		{sign(JWTES256, "es", ecKey, claims(nil)), http.StatusOK},
+		{sign(JWTHS256, "old", []byte("old secret"), claims(nil)), http.StatusOK},
+		{sign(JWTHS256, "hs", []byte("wrong"), claims(nil)), http.StatusUnauthorized},
+		{sign(JWTHS256, "missing", secret, claims(nil)), http.StatusUnauthorized},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"exp": now - 10})), http.StatusOK},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"exp": now - 60})), http.StatusUnauthorized},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"exp": nil})), http.StatusUnauthorized},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"nbf": now + 10})), http.StatusOK},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"nbf": now + 60})), http.StatusUnauthorized},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"iss": "evil"})), http.StatusUnauthorized},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"aud": "wego"})), http.StatusOK},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"aud": "other"})), http.StatusUnauthorized},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"scope": "write"})), http.StatusForbidden},
+		{sign(JWTHS256, "hs", secret, claims(map[string]interface{}{"roles": []string{"user"}})), http.StatusForbidden},
+		{none, http.StatusUnauthorized},
+		{confused, http.StatusUnauthorized},
+		{"not.a.token", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Fatalf("case %d: expected %d, got %d %q", i, tc.code, w.Code, w.Body.String())
+		}
+		if tc.code == http.StatusOK && w.Body.String() != "alice" {
+			t.Fatalf("case %d: unexpected subject %q", i, w.Body.String())
+		}
+		if tc.code == http.StatusUnauthorized && !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+			t.Fatalf("case %d: missing challenge", i)
+		}
+	}
+
+	//未知的kid通过KeyFunc加载
+	rotated := []byte("rotated")
+	config.KeyFunc = func(kid string) (interface{}, error) {
+		if kid == "new" {
+			return rotated, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if _, err := config.Parse(sign(JWTHS256, "new", rotated, claims(nil))); err != nil {
+		t.Fatalf("key should be loaded by KeyFunc: %v", err)
+	}
+	config.Algorithms = []string{JWTRS256}
+	if _, err := config.Parse(sign(JWTHS256, "hs", secret, claims(nil))); err == nil {
+		t.Fatal("disallowed algorithm should be rejected")
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rs","n":%q,"e":%q},
+		{"kty":"EC","kid":"es","crv":"P-256","x":%q,"y":%q},
+		{"kty":"oct","kid":"hs","k":%q},
+		{"kty":"OKP","kid":"ed","crv":"Ed25519","x":"AA"}
+	]}`, enc(rsaKey.N.Bytes()), enc(big.NewInt(int64(rsaKey.E)).Bytes()),
+		enc(ecKey.X.Bytes()), enc(ecKey.Y.Bytes()), enc([]byte("secret")))
+	keys, err := ParseJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("expected 3 keys, got %d", len(keys))
+	}
+	config := JWTConfig{Keys: keys}
+	exp := time.Now().Add(time.Minute).Unix()
+	for kid, key := range map[string]interface{}{"rs": rsaKey, "es": ecKey, "hs": []byte("secret")} {
+		alg := map[string]string{"rs": JWTRS256, "es": JWTES256, "hs": JWTHS256}[kid]
+		token, err := SignJWT(alg, kid, key, map[string]interface{}{"exp": exp})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.Parse(token); err != nil {
+			t.Fatalf("%s: %v", kid, err)
+		}
+	}
+}
@@ -1,14 +1,338 @@
 package wego
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-func Logger() HandlerFunc {
+//日志格式
+const (
+	//LogFormatText 为默认的文本格式,与log包的输出格式相同
+	LogFormatText = iota
+	//LogFormatJSON 每行一个json对象
+	LogFormatJSON
+	//LogFormatCombined 为Apache combined格式,只用于访问日志,其他日志按文本格式输出
+	LogFormatCombined
+)
+
+//日志级别
+const (
+	LevelInfo  = "INFO"
+	LevelWarn  = "WARN"
+	LevelError = "ERROR"
+)
+
+//LogEntry 为一条日志,Access为true时为访问日志
+type LogEntry struct {
+	Time    time.Time
+	Level   string
+	Message string
+
+	//以下字段只用于访问日志
+	Access    bool
+	ClientIP  string
+	Method    string
+	Route     string //匹配到的路由模式,未匹配到路由时为空
+	Path      string
+	URI       string //原始的请求URI,包括查询参数
+	Proto     string
+	Status    int
+	Size      int
+	Latency   time.Duration
+	UserAgent string
+	Referer   string
+	RequestID string
+	TraceID   string
+	User      string //认证中间件设定的 Principal 的Subject
+	Errors    []string
+}
+
+//LogSink 接收日志,需要可以并发调用
+type LogSink interface {
+	Log(entry *LogEntry)
+}
+
+//LogConfig 为 AccessLogger 的设定
+type LogConfig struct {
+	Format int
+	//Output 默认为log包当前的输出,即 log.Writer()
+	Output io.Writer
+	//Sink 不为空时日志交由Sink处理,Format与Output不再使用
+	Sink LogSink
+	//SkipPaths 为不记录访问日志的路径
+	SkipPaths []string
+	//SampleRate 在0到1之间时只记录该比例的访问日志,状态码不小于500或带有错误的请求总是记录
+	SampleRate float64
+}
+
+//AccessLogger 记录访问日志以及引擎自身的日志
+type AccessLogger struct {
+	sink       LogSink
+	skip       map[string]bool
+	sampleRate float64
+}
+
+//NewAccessLogger 创建AccessLogger
+func NewAccessLogger(config LogConfig) *AccessLogger {
+	l := &AccessLogger{sink: config.Sink, skip: make(map[string]bool), sampleRate: config.SampleRate}
+	if l.sink == nil {
+		l.sink = &writerSink{format: config.Format, out: config.Output}
+	}
+	for _, path := range config.SkipPaths {
+		l.skip[path] = true
+	}
+	return l
+}
+
+//Printf 记录INFO级别的日志
+func (l *AccessLogger) Printf(format string, v ...interface{}) {
+	l.logf(LevelInfo, format, v...)
+}
+
+//Warnf 记录WARN级别的日志
+func (l *AccessLogger) Warnf(format string, v ...interface{}) {
+	l.logf(LevelWarn, format, v...)
+}
+
+//Errorf 记录ERROR级别的日志
+func (l *AccessLogger) Errorf(format string, v ...interface{}) {
+	l.logf(LevelError, format, v...)
+}
+
+func (l *AccessLogger) logf(level string, format string, v ...interface{}) {
+	l.sink.Log(&LogEntry{Time: time.Now(), Level: level, Message: fmt.Sprintf(format, v...)})
+}
+
+//Handler 返回记录访问日志的中间件
+func (l *AccessLogger) Handler() HandlerFunc {
 	return func(c *Context) {
-		t := time.Now()
+		l.handle(c)
+	}
+}
+
+func (l *AccessLogger) handle(c *Context) {
+	if l.skip[c.Path] {
 		c.Next()
-		log.Printf("[%d] %s in %v", c.StatusCode, c.Req.RequestURI, time.Since(t))
+		return
+	}
+	start := time.Now()
+	c.Next()
+	status := c.Writer.Status()
+	if l.sampleRate > 0 && l.sampleRate < 1 && status < 500 && len(c.errs) == 0 && rand.Float64() >= l.sampleRate {
+		return
+	}
+	entry := &LogEntry{
+		Time:      start,
+		Level:     LevelInfo,
+		Message:   "request",
+		Access:    true,
+		ClientIP:  c.ClientIP(),
+		Method:    c.Method,
+		Route:     c.FullPath(),
+		Path:      c.Path,
+		URI:       c.Req.RequestURI,
+		Proto:     c.Req.Proto,
+		Status:    status,
+		Size:      c.Writer.Size(),
+		Latency:   time.Since(start),
+		UserAgent: c.Req.UserAgent(),
+		Referer:   c.Req.Referer(),
+		RequestID: c.RequestID(),
+	}
+	if entry.Size < 0 {
+		entry.Size = 0
+	}
+	if entry.URI == "" {
+		//不是由http.Server接收的请求没有RequestURI
+		entry.URI = c.Req.URL.RequestURI()
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = c.Writer.Header().Get(HeaderRequestID)
+	}
+	if sc, ok := c.SpanContext(); ok {
+		entry.TraceID = sc.TraceID.String()
+	}
+	if p := c.Principal(); p != nil {
+		entry.User = p.Subject
+	}
+	for _, err := range c.errs {
+		entry.Errors = append(entry.Errors, err.Error())
+	}
+	if status >= 500 {
+		entry.Level = LevelError
+	} else if status >= 400 {
+		entry.Level = LevelWarn
+	}
+	l.sink.Log(entry)
+}
+
+//Logger 使用引擎的 Engine.Log 记录访问日志
+func Logger() HandlerFunc {
+	return func(c *Context) {
+		c.engine.Log.handle(c)
+	}
+}
+
+//LoggerWithConfig 使用单独的设定记录访问日志
+func LoggerWithConfig(config LogConfig) HandlerFunc {
+	return NewAccessLogger(config).Handler()
+}
+
+//writerSink 按Format将日志写入out
+type writerSink struct {
+	mu     sync.Mutex
+	format int
+	out    io.Writer
+	buf    []byte
+}
+
+func (s *writerSink) Log(e *LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.buf[:0]
+	switch {
+	case s.format == LogFormatJSON:
+		b = appendJSONEntry(b, e)
+	case s.format == LogFormatCombined && e.Access:
+		b = appendCombinedEntry(b, e)
+	default:
+		b = appendTextEntry(b, e)
+	}
+	b = append(b, '\n')
+	out := s.out
+	if out == nil {
+		out = log.Writer()
+	}
+	out.Write(b)
+	s.buf = b
+}
+
+//appendTextEntry 例如 2006/01/02 15:04:05 [200] GET /users/:id 1.2ms 13B 127.0.0.1 "curl/7.68.0"
+func appendTextEntry(b []byte, e *LogEntry) []byte {
+	b = e.Time.AppendFormat(b, "2006/01/02 15:04:05 ")
+	if !e.Access {
+		if e.Level != LevelInfo {
+			b = append(b, "["+e.Level+"] "...)
+		}
+		return append(b, e.Message...)
+	}
+	route := e.Route
+	if route == "" {
+		route = e.Path
+	}
+	b = append(b, '[')
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, "] "+e.Method+" "...)
+	b = appendEscaped(b, route)
+	b = append(b, " "+e.Latency.String()+" "...)
+	b = strconv.AppendInt(b, int64(e.Size), 10)
+	b = append(b, "B "+e.ClientIP+" "...)
+	b = strconv.AppendQuote(b, e.UserAgent)
+	if e.RequestID != "" {
+		b = append(b, " request_id="+e.RequestID...)
+	}
+	if e.TraceID != "" {
+		b = append(b, " trace_id="+e.TraceID...)
+	}
+	if e.User != "" {
+		b = append(b, " user="...)
+		b = appendEscaped(b, e.User)
+	}
+	if len(e.Errors) > 0 {
+		b = append(b, " errors="...)
+		b = strconv.AppendQuote(b, strings.Join(e.Errors, "; "))
+	}
+	return b
+}
+
+//appendCombinedEntry 使用Apache combined格式,请求行中为原始的请求URI
+func appendCombinedEntry(b []byte, e *LogEntry) []byte {
+	user := e.User
+	if user == "" {
+		user = "-"
+	}
+	b = append(b, e.ClientIP+" - "...)
+	b = appendEscaped(b, user)
+	b = append(b, " ["...)
+	b = e.Time.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+	b = append(b, "] \""+e.Method+" "...)
+	b = appendEscaped(b, e.URI)
+	b = append(b, " "+e.Proto+"\" "...)
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, ' ')
+	if e.Size > 0 {
+		b = strconv.AppendInt(b, int64(e.Size), 10)
+	} else {
+		b = append(b, '-')
+	}
+	referer, userAgent := e.Referer, e.UserAgent
+	if referer == "" {
+		referer = "-"
+	}
+	if userAgent == "" {
+		userAgent = "-"
+	}
+	b = append(b, " \""...)
+	b = appendEscaped(b, referer)
+	b = append(b, "\" \""...)
+	b = appendEscaped(b, userAgent)
+	return append(b, '"')
+}
+
+//appendEscaped 与Apache相同,将双引号与反斜杠转义,控制字符写为\xhh,防止请求伪造日志中的行与字段
+func appendEscaped(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"' || ch == '\\':
+			b = append(b, '\\', ch)
+		case ch < 0x20 || ch == 0x7f:
+			b = append(b, '\\', 'x', hex[ch>>4], hex[ch&0xf])
+		default:
+			b = append(b, ch)
+		}
+	}
+	return b
+}
+
+//jsonEntry 决定json格式中字段的名称与顺序
+type jsonEntry struct {
+	Time      string   `json:"time"`
+	Level     string   `json:"level"`
+	Message   string   `json:"msg"`
+	ClientIP  string   `json:"client_ip,omitempty"`
+	Method    string   `json:"method,omitempty"`
+	Route     string   `json:"route,omitempty"`
+	Path      string   `json:"path,omitempty"`
+	Status    int      `json:"status,omitempty"`
+	Size      *int     `json:"bytes,omitempty"`
+	LatencyMS *float64 `json:"latency_ms,omitempty"`
+	UserAgent string   `json:"user_agent,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+	TraceID   string   `json:"trace_id,omitempty"`
+	User      string   `json:"user,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+func appendJSONEntry(b []byte, e *LogEntry) []byte {
+	j := jsonEntry{Time: e.Time.Format(time.RFC3339Nano), Level: e.Level, Message: e.Message}
+	if e.Access {
+		latency := float64(e.Latency) / float64(time.Millisecond)
+		size := e.Size
+		j.ClientIP, j.Method, j.Route, j.Path = e.ClientIP, e.Method, e.Route, e.Path
+		j.Status, j.Size, j.LatencyMS = e.Status, &size, &latency
+		j.UserAgent, j.RequestID, j.TraceID, j.User, j.Errors = e.UserAgent, e.RequestID, e.TraceID, e.User, e.Errors
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return append(b, `{"level":"ERROR","msg":"wego: failed to encode log entry"}`...)
 	}
+	return append(b, data...)
 }
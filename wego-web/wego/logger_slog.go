@@ -0,0 +1,59 @@
+//go:build go1.21
+// +build go1.21
+
+package wego
+
+import (
+	"context"
+	"log/slog"
+)
+
+//SlogSink 返回将日志交由slog.Handler处理的LogSink,需要Go 1.21及以上版本
+//	访问日志的各个字段作为slog的属性,级别对应slog.LevelInfo、LevelWarn与LevelError
+func SlogSink(handler slog.Handler) LogSink {
+	return &slogSink{handler: handler}
+}
+
+type slogSink struct {
+	handler slog.Handler
+}
+
+func (s *slogSink) Log(e *LogEntry) {
+	level := slog.LevelInfo
+	switch e.Level {
+	case LevelWarn:
+		level = slog.LevelWarn
+	case LevelError:
+		level = slog.LevelError
+	}
+	ctx := context.Background()
+	if !s.handler.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(e.Time, level, e.Message, 0)
+	if e.Access {
+		r.AddAttrs(
+			slog.String("client_ip", e.ClientIP),
+			slog.String("method", e.Method),
+			slog.String("route", e.Route),
+			slog.String("path", e.Path),
+			slog.Int("status", e.Status),
+			slog.Int("bytes", e.Size),
+			slog.Duration("latency", e.Latency),
+			slog.String("user_agent", e.UserAgent),
+		)
+		if e.RequestID != "" {
+			r.AddAttrs(slog.String("request_id", e.RequestID))
+		}
+		if e.TraceID != "" {
+			r.AddAttrs(slog.String("trace_id", e.TraceID))
+		}
+		if e.User != "" {
+			r.AddAttrs(slog.String("user", e.User))
+		}
+		if len(e.Errors) > 0 {
+			r.AddAttrs(slog.Any("errors", e.Errors))
+		}
+	}
+	s.handler.Handle(ctx, r)
+}
@@ -0,0 +1,34 @@
+//go:build go1.21
+// +build go1.21
+
+package wego
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlogSink(t *testing.T) {
+	var out bytes.Buffer
+	r := New()
+	r.Log = NewAccessLogger(LogConfig{Sink: SlogSink(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelWarn}))})
+	r.Use(Logger())
+	r.GET("/ok", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	//INFO级别的路由注册与/ok的访问日志被过滤
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single json entry, got %q: %v", out.String(), err)
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "request" || entry["path"] != "/missing" || entry["status"] != float64(404) {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+}
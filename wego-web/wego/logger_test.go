@@ -0,0 +1,138 @@
+package wego
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLoggerFormats(t *testing.T) {
+	for _, tc := range []struct {
+		format  int
+		pattern string
+	}{
+		{LogFormatText, `^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d \[404\] GET /users/:id \S+ 9B 192\.0\.2\.1 "wego-test" request_id=abc user=tom errors="user not found"$`},
+		{LogFormatCombined, `^192\.0\.2\.1 - tom \[\d\d/\w{3}/\d{4}:\d\d:\d\d:\d\d [+-]\d{4}\] "GET /users/1\?tab=posts HTTP/1\.1" 404 9 "https://example\.com/" "wego-test"$`},
+	} {
+		var out bytes.Buffer
+		r := New()
+		r.Log = NewAccessLogger(LogConfig{Format: tc.format, Output: &out})
+		r.Use(Logger())
+		r.GET("/users/:id", func(c *Context) {
+			c.SetPrincipal(&Principal{Subject: "tom"})
+			c.SetHeader("X-Request-ID", "abc")
+			c.errs = append(c.errs, errString("user not found"))
+			c.Data(http.StatusNotFound, []byte("not found"))
+		})
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 1 || !strings.HasSuffix(lines[0], "Route  GET - /users/:id") {
+			t.Fatalf("route registration should be logged through the engine logger, got %q", out.String())
+		}
+		out.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, "/users/1?tab=posts", nil)
+		req.Header.Set("User-Agent", "wego-test")
+		req.Header.Set("Referer", "https://example.com/")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if line := strings.TrimSuffix(out.String(), "\n"); !regexp.MustCompile(tc.pattern).MatchString(line) {
+			t.Fatalf("format %d: unexpected log line %q", tc.format, line)
+		}
+	}
+}
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
+
+func TestLoggerJSON(t *testing.T) {
+	var out bytes.Buffer
+	r := New()
+	r.Use(LoggerWithConfig(LogConfig{Format: LogFormatJSON, Output: &out, SkipPaths: []string{"/health"}}))
+	r.GET("/health", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/fail", func(c *Context) {
+		c.Fail(http.StatusInternalServerError, "boom")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	if out.Len() != 0 {
+		t.Fatalf("skipped paths should not be logged, got %q", out.String())
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid json log %q: %v", out.String(), err)
+	}
+	if entry["level"] != LevelError || entry["route"] != "/fail" || entry["status"] != float64(500) || entry["errors"].([]interface{})[0] != "boom" {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+	if _, ok := entry["latency_ms"].(float64); !ok {
+		t.Fatalf("latency should be logged, got %v", entry)
+	}
+}
+
+type entrySink []*LogEntry
+
+func (s *entrySink) Log(e *LogEntry) { *s = append(*s, e) }
+
+func TestLoggerSampling(t *testing.T) {
+	var sink entrySink
+	r := New()
+	r.Use(LoggerWithConfig(LogConfig{Sink: &sink, SampleRate: 0.1}))
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/error", func(c *Context) {
+		c.Status(http.StatusBadGateway)
+	})
+	for i := 0; i < 1000; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if len(sink) < 50 || len(sink) > 200 {
+		t.Fatalf("about 100 of 1000 requests should be logged, got %d", len(sink))
+	}
+	sink = sink[:0]
+	for i := 0; i < 10; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/error", nil))
+	}
+	if len(sink) != 10 || sink[0].Level != LevelError {
+		t.Fatalf("server errors should always be logged, got %d", len(sink))
+	}
+}
+
+func TestLoggerEscape(t *testing.T) {
+	for _, tc := range []struct {
+		format int
+		expect string
+	}{
+		{LogFormatText, ` [404] GET /a\x0d\x0a2021/01/01 00:00:00 [200] GET /admin `},
+		{LogFormatCombined, `"GET /a%0D%0A2021/01/01%2000:00:00%20[200]%20GET%20/admin HTTP/1.1" 404`},
+	} {
+		var out bytes.Buffer
+		r := New()
+		r.Log = NewAccessLogger(LogConfig{Format: tc.format, Output: &out})
+		r.Use(Logger())
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a%0D%0A2021/01/01%2000:00:00%20[200]%20GET%20/admin", nil))
+		if line := strings.TrimSuffix(out.String(), "\n"); strings.Contains(line, "\n") || !strings.Contains(line, tc.expect) {
+			t.Fatalf("format %d: request paths should be escaped, got %q", tc.format, line)
+		}
+	}
+
+	var out bytes.Buffer
+	r := New()
+	r.Log = NewAccessLogger(LogConfig{Output: &out})
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+		c.Status(http.StatusCreated)
+	})
+	out.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(out.String(), "[WARN] Headers were already written") {
+		t.Fatalf("warnings of the response writer should use the engine logger, got %q", out.String())
+	}
+}
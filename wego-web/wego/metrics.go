@@ -0,0 +1,460 @@
+package wego
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//指标类型
+const (
+	metricCounter   = "counter"
+	metricGauge     = "gauge"
+	metricHistogram = "histogram"
+)
+
+//DefBuckets 为请求耗时直方图默认的桶,单位为秒
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+//Registry 保存所有的指标,并以Prometheus文本格式输出
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]*metric
+}
+
+//DefaultRegistry 为 Metrics 与 MetricsHandler 默认使用的Registry
+var DefaultRegistry = NewRegistry()
+
+//NewRegistry 创建Registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+//metric 为同名的一组时间序列
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.RWMutex
+	series map[string]*series //键为以0xff分隔的标签值
+}
+
+//series 为一条时间序列,counter与gauge使用value,histogram使用其余字段
+type series struct {
+	labelValues []string
+	value       uint64 //float64的位表示,原子操作
+
+	mu     sync.Mutex
+	counts []uint64 //每个桶的计数,不累加
+	sum    float64
+	count  uint64
+}
+
+//register 注册指标,同名指标已存在且定义相同时返回已有的指标,否则panic
+func (r *Registry) register(name, help, kind string, buckets []float64, labels []string) *metric {
+	if !validMetricName(name) {
+		panic("wego: invalid metric name " + strconv.Quote(name))
+	}
+	for _, label := range labels {
+		if !validMetricName(label) || strings.Contains(label, ":") || label == "le" || strings.HasPrefix(label, "__") {
+			panic("wego: invalid label name " + strconv.Quote(label))
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		if m.kind != kind || strings.Join(m.labels, ",") != strings.Join(labels, ",") {
+			panic("wego: metric " + name + " is already registered with a different definition")
+		}
+		return m
+	}
+	m := &metric{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	r.metrics[name] = m
+	return m
+}
+
+func validMetricName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, ch := range name {
+		if !(ch == '_' || ch == ':' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (i > 0 && ch >= '0' && ch <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+//with 返回标签值对应的时间序列,不存在时创建
+func (m *metric) with(values []string) *series {
+	if len(values) != len(m.labels) {
+		panic("wego: metric " + m.name + " expects " + strconv.Itoa(len(m.labels)) + " label values")
+	}
+	key := strings.Join(values, "\xff")
+	m.mu.RLock()
+	s, ok := m.series[key]
+	m.mu.RUnlock()
+	if ok {
+		return s
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok = m.series[key]; !ok {
+		s = &series{labelValues: append([]string(nil), values...)}
+		if m.kind == metricHistogram {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+func (s *series) add(v float64) {
+	for {
+		old := atomic.LoadUint64(&s.value)
+		if atomic.CompareAndSwapUint64(&s.value, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (s *series) load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&s.value))
+}
+
+//Counter 为只增不减的计数器
+type Counter struct{ s *series }
+
+//Inc 加1
+func (c Counter) Inc() { c.s.add(1) }
+
+//Add 增加v,v不能为负数
+func (c Counter) Add(v float64) {
+	if v < 0 {
+		panic("wego: counter cannot decrease")
+	}
+	c.s.add(v)
+}
+
+//CounterVec 为带有标签的一组计数器
+type CounterVec struct{ m *metric }
+
+//NewCounterVec 注册名为name的计数器,labels为标签名
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, metricCounter, nil, labels)}
+}
+
+//NewCounter 注册没有标签的计数器
+func (r *Registry) NewCounter(name, help string) Counter {
+	return r.NewCounterVec(name, help).WithLabelValues()
+}
+
+//WithLabelValues 返回按标签名的顺序给出的标签值对应的计数器
+func (v *CounterVec) WithLabelValues(values ...string) Counter {
+	return Counter{v.m.with(values)}
+}
+
+//Gauge 为可增可减的值
+type Gauge struct{ s *series }
+
+//Inc 加1
+func (g Gauge) Inc() { g.s.add(1) }
+
+//Dec 减1
+func (g Gauge) Dec() { g.s.add(-1) }
+
+//Add 增加v,v可以为负数
+func (g Gauge) Add(v float64) { g.s.add(v) }
+
+//Set 设定为v
+func (g Gauge) Set(v float64) { atomic.StoreUint64(&g.s.value, math.Float64bits(v)) }
+
+//Value 返回当前的值
+func (g Gauge) Value() float64 { return g.s.load() }
+
+//GaugeVec 为带有标签的一组Gauge
+type GaugeVec struct{ m *metric }
+
+//NewGaugeVec 注册名为name的Gauge
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, metricGauge, nil, labels)}
+}
+
+//NewGauge 注册没有标签的Gauge
+func (r *Registry) NewGauge(name, help string) Gauge {
+	return r.NewGaugeVec(name, help).WithLabelValues()
+}
+
+//WithLabelValues 返回按标签名的顺序给出的标签值对应的Gauge
+func (v *GaugeVec) WithLabelValues(values ...string) Gauge {
+	return Gauge{v.m.with(values)}
+}
+
+//Histogram 按桶统计观测值的分布
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+//Observe 记录一个观测值
+func (h Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v) //第一个不小于v的桶
+	h.s.mu.Lock()
+	if i < len(h.buckets) {
+		h.s.counts[i]++
+	}
+	h.s.sum += v
+	h.s.count++
+	h.s.mu.Unlock()
+}
+
+//HistogramVec 为带有标签的一组Histogram
+type HistogramVec struct{ m *metric }
+
+//NewHistogramVec 注册名为name的直方图,buckets为各个桶的上界,为空时使用 DefBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	if math.IsInf(buckets[len(buckets)-1], 1) {
+		//+Inf桶总是会输出
+		buckets = buckets[:len(buckets)-1]
+	}
+	return &HistogramVec{r.register(name, help, metricHistogram, buckets, labels)}
+}
+
+//NewHistogram 注册没有标签的直方图
+func (r *Registry) NewHistogram(name, help string, buckets []float64) Histogram {
+	return r.NewHistogramVec(name, help, buckets).WithLabelValues()
+}
+
+//WithLabelValues 返回按标签名的顺序给出的标签值对应的直方图
+func (v *HistogramVec) WithLabelValues(values ...string) Histogram {
+	return Histogram{v.m.with(values), v.m.buckets}
+}
+
+//WriteTo 以Prometheus文本格式(0.0.4)输出所有指标,指标与时间序列按名称和标签值排序
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.RUnlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, m := range metrics {
+		m.write(cw)
+	}
+	err := cw.w.Flush()
+	if cw.err != nil {
+		err = cw.err
+	}
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) WriteString(s string) {
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	if err != nil && cw.err == nil {
+		cw.err = err
+	}
+}
+
+func (m *metric) write(w *countingWriter) {
+	m.mu.RLock()
+	all := make([]*series, 0, len(m.series))
+	for _, s := range m.series {
+		all = append(all, s)
+	}
+	m.mu.RUnlock()
+	if len(all) == 0 {
+		return
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i].labelValues, all[j].labelValues
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+
+	w.WriteString("# HELP " + m.name + " " + escapeHelp(m.help) + "\n")
+	w.WriteString("# TYPE " + m.name + " " + m.kind + "\n")
+	for _, s := range all {
+		if m.kind != metricHistogram {
+			w.WriteString(m.name + formatLabels(m.labels, s.labelValues, "") + " " + formatFloat(s.load()) + "\n")
+			continue
+		}
+		s.mu.Lock()
+		counts, sum, count := append([]uint64(nil), s.counts...), s.sum, s.count
+		s.mu.Unlock()
+		var cumulative uint64
+		for i, upper := range m.buckets {
+			cumulative += counts[i]
+			w.WriteString(m.name + "_bucket" + formatLabels(m.labels, s.labelValues, formatFloat(upper)) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		w.WriteString(m.name + "_bucket" + formatLabels(m.labels, s.labelValues, "+Inf") + " " + strconv.FormatUint(count, 10) + "\n")
+		w.WriteString(m.name + "_sum" + formatLabels(m.labels, s.labelValues, "") + " " + formatFloat(sum) + "\n")
+		w.WriteString(m.name + "_count" + formatLabels(m.labels, s.labelValues, "") + " " + strconv.FormatUint(count, 10) + "\n")
+	}
+}
+
+//formatLabels 返回 {a="1",b="2"} ,le不为空时添加直方图的le标签
+func formatLabels(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name + `="` + escapeLabelValue(values[i]) + `"`)
+	}
+	if le != "" {
+		if len(names) > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`le="` + le + `"`)
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+//Handler 返回以Prometheus文本格式输出所有指标的处理器,可以挂载在任意的RouterGroup上
+func (r *Registry) Handler() HandlerFunc {
+	return func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		r.WriteTo(c.Writer)
+	}
+}
+
+//MetricsHandler 返回输出 DefaultRegistry 中所有指标的处理器
+func MetricsHandler() HandlerFunc {
+	return DefaultRegistry.Handler()
+}
+
+//MetricsConfig 为 Metrics 中间件的设定
+type MetricsConfig struct {
+	//Registry 默认为 DefaultRegistry
+	Registry *Registry
+	//Namespace 为指标名称的前缀,默认为 wego
+	Namespace string
+	//DurationBuckets 为请求耗时直方图的桶,默认为 DefBuckets
+	DurationBuckets []float64
+	//SizeBuckets 为响应体大小直方图的桶,默认为100B到100MB
+	SizeBuckets []float64
+	//SkipPaths 为不记录的路径,如 /metrics
+	SkipPaths []string
+}
+
+//Metrics 按请求方法、路由模式与状态码记录请求数、耗时、响应大小以及处理中的请求数
+//	路由标签使用匹配到的路由模式,未匹配到路由时为 unmatched ,非常用的请求方法记为 OTHER ,使时间序列的数量有限
+func Metrics(config MetricsConfig) HandlerFunc {
+	if config.Registry == nil {
+		config.Registry = DefaultRegistry
+	}
+	if config.Namespace == "" {
+		config.Namespace = "wego"
+	}
+	if config.SizeBuckets == nil {
+		config.SizeBuckets = []float64{100, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8}
+	}
+	skip := make(map[string]bool, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skip[path] = true
+	}
+	reg, ns := config.Registry, config.Namespace
+	requests := reg.NewCounterVec(ns+"_http_requests_total", "Total number of HTTP requests.", "method", "route", "status")
+	duration := reg.NewHistogramVec(ns+"_http_request_duration_seconds", "HTTP request latency in seconds.", config.DurationBuckets, "method", "route")
+	size := reg.NewHistogramVec(ns+"_http_response_size_bytes", "HTTP response body size in bytes.", config.SizeBuckets, "method", "route")
+	inFlight := reg.NewGauge(ns+"_http_requests_in_flight", "Number of HTTP requests being served.")
+
+	return func(c *Context) {
+		if skip[c.Path] {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		inFlight.Inc()
+		panicked := true
+		defer func() {
+			inFlight.Dec()
+			status := c.Writer.Status()
+			if panicked {
+				//处理器panic时响应由外层的 Recovery 写出,记为500;不在这里recover,保留原始的调用堆栈
+				status = http.StatusInternalServerError
+			}
+			method := c.Method
+			if !isStandardMethod(method) {
+				method = "OTHER"
+			}
+			route := c.FullPath()
+			if route == "" {
+				route = "unmatched"
+			}
+			requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+			duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			written := c.Writer.Size()
+			if written < 0 {
+				written = 0
+			}
+			size.WithLabelValues(method, route).Observe(float64(written))
+		}()
+		c.Next()
+		panicked = false
+	}
+}
+
+func isStandardMethod(method string) bool {
+	for _, m := range anyMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryExposition(t *testing.T) {
+	reg := NewRegistry()
+	requests := reg.NewCounterVec("jobs_total", "Jobs processed.\nBy queue.", "queue")
+	requests.WithLabelValues(`a"b\c`).Add(2)
+	requests.WithLabelValues("default").Inc()
+	temp := reg.NewGauge("temperature", "Current temperature.")
+	temp.Set(21.5)
+	temp.Dec()
+	latency := reg.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1})
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		latency.Observe(v)
+	}
+	reg.NewCounterVec("unused_total", "Never observed.", "x")
+	if reg.NewCounterVec("jobs_total", "Jobs processed.", "queue") == nil {
+		t.Fatal("registering the same metric twice should return the existing one")
+	}
+
+	var sb strings.Builder
+	reg.WriteTo(&sb)
+	expected := `# HELP jobs_total Jobs processed.\nBy queue.
+# TYPE jobs_total counter
+jobs_total{queue="a\"b\\c"} 2
+jobs_total{queue="default"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 3.65
+latency_seconds_count 4
+# HELP temperature Current temperature.
+# TYPE temperature gauge
+temperature 20.5
+`
+	if sb.String() != expected {
+		t.Fatalf("unexpected exposition:\n%s", sb.String())
+	}
+
+	for _, register := range []func(){
+		func() { reg.NewGaugeVec("jobs_total", "Conflicting type.", "queue") },
+		func() { reg.NewCounterVec("jobs_total", "Conflicting labels.", "queue", "host") },
+		func() { reg.NewCounter("bad-name", "Invalid name.") },
+		func() { reg.NewCounterVec("ok_total", "Reserved label.", "le") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("invalid registration should panic")
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := NewRegistry()
+	r := New()
+	var logs entrySink
+	r.Log = NewAccessLogger(LogConfig{Sink: &logs})
+	r.Use(Recovery())
+	r.Use(Metrics(MetricsConfig{Registry: reg, DurationBuckets: []float64{1}, SizeBuckets: []float64{10}, SkipPaths: []string{"/metrics"}}))
+	r.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "user")
+	})
+	r.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+	admin := r.Group("/admin")
+	admin.GET("/metrics", reg.Handler())
+	r.GET("/metrics", reg.Handler())
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing/1", "/missing/2", "/panic"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/users/1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+	if w.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`wego_http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`wego_http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+		`wego_http_requests_total{method="OTHER",route="unmatched",status="405"} 1`,
+		//panic由外层的Recovery处理
+		`wego_http_requests_total{method="GET",route="/panic",status="500"} 1`,
+		`wego_http_request_duration_seconds_count{method="GET",route="/users/:id"} 2`,
+		`wego_http_response_size_bytes_bucket{method="GET",route="/users/:id",le="10"} 2`,
+		`wego_http_response_size_bytes_sum{method="GET",route="/users/:id"} 8`,
+		//正在处理/admin/metrics
+		`wego_http_requests_in_flight 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, body)
+		}
+	}
+	for _, e := range logs {
+		if !strings.HasPrefix(e.Message, "boom") {
+			continue
+		}
+		//Recovery记录的调用堆栈应从panic的位置开始,而不是Metrics中
+		site, i := strings.Index(e.Message, "metrics_test.go"), strings.Index(e.Message, "metrics.go")
+		if site < 0 || (i >= 0 && i < site) {
+			t.Fatalf("traceback should start at the panic site:\n%s", e.Message)
+		}
+	}
+	if strings.Contains(body, `route="/metrics"`) || strings.Contains(body, "/missing/") {
+		t.Fatalf("skipped paths and raw paths should not be recorded:\n%s", body)
+	}
+}
@@ -0,0 +1,42 @@
+package wego
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+//运行模式
+const (
+	//DebugMode 为默认的模式,错误响应中包含内部错误的详细信息,模板修改后自动重新加载
+	DebugMode = "debug"
+	//ReleaseMode 用于生产环境,隐藏内部错误的详细信息
+	ReleaseMode = "release"
+	//TestMode 用于测试
+	TestMode = "test"
+)
+
+//EnvWegoMode 为设定初始运行模式的环境变量
+const EnvWegoMode = "WEGO_MODE"
+
+var mode atomic.Value
+
+func init() {
+	SetMode(os.Getenv(EnvWegoMode))
+}
+
+//SetMode 设定运行模式,为空时使用 DebugMode ,其他值会panic
+func SetMode(value string) {
+	switch value {
+	case "":
+		value = DebugMode
+	case DebugMode, ReleaseMode, TestMode:
+	default:
+		panic("wego: unknown mode " + value + " (available modes: debug, release, test)")
+	}
+	mode.Store(value)
+}
+
+//Mode 返回当前的运行模式
+func Mode() string {
+	return mode.Load().(string)
+}
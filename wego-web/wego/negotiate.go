@@ -0,0 +1,135 @@
+package wego
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//Negotiate 为 Context.Negotiate 的参数
+//	Offered 为服务端可以返回的MIME类型,按优先级排列
+//	JSONData、XMLData、YAMLData、HTMLData、ProtoBufData 为对应格式返回的数据,为nil时使用Data
+type Negotiate struct {
+	Offered      []string
+	HTMLName     string //返回html时使用的模板名称
+	HTMLData     interface{}
+	JSONData     interface{}
+	XMLData      interface{}
+	YAMLData     interface{}
+	ProtoBufData interface{}
+	Data         interface{}
+}
+
+//Negotiate 根据请求头Accept在config.Offered中选择返回的格式,没有可接受的格式时返回406
+func (c *Context) Negotiate(code int, config Negotiate) {
+	c.Writer.Header().Add("Vary", "Accept")
+	switch c.NegotiateFormat(config.Offered...) {
+	case MIMEJSON:
+		c.JSON(code, pick(config.JSONData, config.Data))
+	case MIMEHTML:
+		c.HTMLTemplate(code, config.HTMLName, pick(config.HTMLData, config.Data))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, pick(config.XMLData, config.Data))
+	case MIMEYAML:
+		c.YAML(code, pick(config.YAMLData, config.Data))
+	case MIMEProtoBuf:
+		c.ProtoBuf(code, pick(config.ProtoBufData, config.Data))
+	default:
+		c.AbortWithStatusJSON(http.StatusNotAcceptable, H{"message": "the accepted formats are not offered by the server"})
+	}
+}
+
+func pick(data interface{}, fallback interface{}) interface{} {
+	if data != nil {
+		return data
+	}
+	return fallback
+}
+
+//NegotiateFormat 返回offered中与请求头Accept最匹配的MIME类型,没有可接受的类型时返回空字符串
+//	未设置Accept时返回offered[0]
+//	每个类型的q值取自与其匹配的最具体的一项,因此 application/json;q=0, */* 不会选择json
+//	q值相同时匹配更具体的类型优先,仍相同时按offered中的顺序
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		panic("wego: you must provide at least one offer")
+	}
+	accept := c.Req.Header.Get("Accept")
+	if accept == "" {
+		return offered[0]
+	}
+	specs := parseAccept(accept)
+	best, bestQ, bestSpecificity := "", 0.0, -1
+	for _, offer := range offered {
+		q, specificity := 0.0, -1
+		for _, spec := range specs {
+			if spec.matches(offer) && spec.specificity() > specificity {
+				q, specificity = spec.q, spec.specificity()
+			}
+		}
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return best
+}
+
+//acceptSpec 为Accept中的一项
+type acceptSpec struct {
+	typ, subtype string
+	q            float64
+}
+
+//specificity 返回类型的具体程度, */* 为0, type/* 为1, type/subtype 为2
+func (s acceptSpec) specificity() int {
+	switch {
+	case s.typ == "*":
+		return 0
+	case s.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+func (s acceptSpec) matches(offer string) bool {
+	typ, subtype := splitMIME(offer)
+	return (s.typ == "*" || s.typ == typ) && (s.subtype == "*" || s.subtype == subtype)
+}
+
+//parseAccept 解析请求头Accept,忽略格式错误的项,q值无效时视为0
+func parseAccept(accept string) []acceptSpec {
+	specs := make([]acceptSpec, 0, 4)
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		typ, subtype := splitMIME(fields[0])
+		if typ == "" || subtype == "" {
+			continue
+		}
+		spec := acceptSpec{typ: typ, subtype: subtype, q: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if len(param) > 2 && (param[0] == 'q' || param[0] == 'Q') && param[1] == '=' {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				spec.q = q
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+//splitMIME 将MIME类型拆分为类型与子类型,忽略参数并转为小写
+func splitMIME(mime string) (string, string) {
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	i := strings.IndexByte(mime, '/')
+	if i < 0 {
+		return "", ""
+	}
+	return strings.TrimSpace(mime[:i]), strings.TrimSpace(mime[i+1:])
+}
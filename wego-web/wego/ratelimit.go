@@ -0,0 +1,243 @@
+package wego
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//限流算法
+const (
+	//RateLimitTokenBucket 令牌桶,以固定速率补充令牌,允许不超过Burst的突发请求
+	RateLimitTokenBucket = iota
+	//RateLimitSlidingWindow 滑动窗口,按当前窗口与上一个窗口的请求数加权计算
+	RateLimitSlidingWindow
+)
+
+//LimitRule 为一条限流规则,在Window内最多允许Limit个请求
+type LimitRule struct {
+	Algorithm int
+	Limit     int
+	Window    time.Duration
+	Burst     int //令牌桶的容量,滑动窗口不使用
+}
+
+//LimitResult 为一次限流判断的结果
+type LimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration //限额完全恢复的时间
+	RetryAfter time.Duration //被拒绝时需要等待的时间
+}
+
+//LimitStore 保存限流状态,多个进程共享限额时可以使用外部存储实现
+type LimitStore interface {
+	//Take 为key消耗一个请求的限额
+	Take(key string, rule LimitRule) (LimitResult, error)
+}
+
+//RateLimitConfig 为 RateLimit 中间件的设定
+type RateLimitConfig struct {
+	Algorithm int           //默认为 RateLimitTokenBucket
+	Limit     int           //Window内允许的请求数
+	Window    time.Duration //默认为1分钟
+	Burst     int           //令牌桶的容量,默认等于Limit
+	//KeyFunc 返回限流的键,默认为 KeyByIP ,返回空字符串时不限流
+	KeyFunc func(c *Context) string
+	//Prefix 为键的前缀,多个限流器共用一个LimitStore时用于区分
+	Prefix string
+	//Store 默认为每个中间件单独的 MemoryLimitStore
+	Store LimitStore
+}
+
+//RateLimit 超出限额时返回429,并设定 X-RateLimit-Limit 、 X-RateLimit-Remaining 、 X-RateLimit-Reset 与 Retry-After
+//	LimitStore返回错误时记录日志并放行请求
+func RateLimit(config RateLimitConfig) HandlerFunc {
+	if config.Limit <= 0 {
+		panic("wego: RateLimit requires a positive limit")
+	}
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	if config.Burst <= 0 {
+		config.Burst = config.Limit
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByIP
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryLimitStore()
+	}
+	rule := LimitRule{Algorithm: config.Algorithm, Limit: config.Limit, Window: config.Window, Burst: config.Burst}
+	return func(c *Context) {
+		key := config.KeyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		result, err := config.Store.Take(config.Prefix+key, rule)
+		if err != nil {
+			c.engine.Log.Errorf("rate limit %s: %v", key, err)
+			c.Next()
+			return
+		}
+		header := c.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, H{"message": http.StatusText(http.StatusTooManyRequests)})
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+//KeyByIP 按客户端IP限流
+func KeyByIP(c *Context) string {
+	return c.ClientIP()
+}
+
+//KeyByHeader 按请求头name的值限流,如API key
+func KeyByHeader(name string) func(c *Context) string {
+	return func(c *Context) string {
+		return c.Req.Header.Get(name)
+	}
+}
+
+//KeyByRoute 按路由限流,同一路由的所有请求共享限额
+func KeyByRoute(c *Context) string {
+	return c.Method + " " + c.FullPath()
+}
+
+//MemoryLimitStore 在内存中保存限流状态,只适用于单个进程
+type MemoryLimitStore struct {
+	mu        sync.Mutex
+	states    map[string]*limitState
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type limitState struct {
+	//令牌桶
+	tokens float64
+	//滑动窗口
+	start    time.Time
+	previous int
+	current  int
+
+	last time.Time
+	//expires 之后状态已经完全恢复,可以被清除
+	expires time.Time
+}
+
+//limitSweepInterval 为清除过期状态的间隔
+const limitSweepInterval = time.Minute
+
+//NewMemoryLimitStore 创建MemoryLimitStore,长时间未使用的键会被定期清除
+func NewMemoryLimitStore() *MemoryLimitStore {
+	return &MemoryLimitStore{states: make(map[string]*limitState), lastSweep: time.Now(), now: time.Now}
+}
+
+func (s *MemoryLimitStore) Take(key string, rule LimitRule) (LimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastSweep) > limitSweepInterval {
+		//过期的状态已经完全恢复,删除后与新建的状态相同
+		//	每个状态按写入时的规则计算过期时间,多个限流器共用时互不影响
+		for k, state := range s.states {
+			if now.After(state.expires) {
+				delete(s.states, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	state, ok := s.states[key]
+	if !ok {
+		state = &limitState{tokens: float64(rule.Burst), start: now.Truncate(rule.Window)}
+		s.states[key] = state
+	}
+	var result LimitResult
+	if rule.Algorithm == RateLimitSlidingWindow {
+		result = state.slidingWindow(rule, now)
+	} else {
+		result = state.tokenBucket(rule, now)
+	}
+	state.last = now
+	state.expires = now.Add(rule.idle())
+	return result, nil
+}
+
+//idle 返回状态在最后一次请求后完全恢复所需的时间
+func (rule LimitRule) idle() time.Duration {
+	idle := 2 * rule.Window
+	if rule.Burst > rule.Limit {
+		idle += rule.Window * time.Duration(rule.Burst) / time.Duration(rule.Limit)
+	}
+	return idle
+}
+
+func (state *limitState) tokenBucket(rule LimitRule, now time.Time) LimitResult {
+	rate := float64(rule.Limit) / rule.Window.Seconds() //每秒补充的令牌数
+	if !state.last.IsZero() {
+		state.tokens = math.Min(float64(rule.Burst), state.tokens+now.Sub(state.last).Seconds()*rate)
+	}
+	result := LimitResult{Limit: rule.Burst}
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - state.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(state.tokens)
+	result.Reset = time.Duration((float64(rule.Burst) - state.tokens) / rate * float64(time.Second))
+	return result
+}
+
+func (state *limitState) slidingWindow(rule LimitRule, now time.Time) LimitResult {
+	start := now.Truncate(rule.Window)
+	if !start.Equal(state.start) {
+		if start.Sub(state.start) == rule.Window {
+			state.previous = state.current
+		} else {
+			state.previous = 0
+		}
+		state.current = 0
+		state.start = start
+	}
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(rule.Window)
+	count := float64(state.previous)*weight + float64(state.current)
+	result := LimitResult{Limit: rule.Limit, Reset: rule.Window - elapsed}
+	if count+1 <= float64(rule.Limit) {
+		state.current++
+		count++
+		result.Allowed = true
+	} else if state.current+1 > rule.Limit {
+		//当前窗口的请求数已经用完,在下一个窗口中作为上一个窗口的请求数,需要等待其权重降低
+		need := 1 - float64(rule.Limit-1)/float64(state.current)
+		result.RetryAfter = rule.Window - elapsed + time.Duration(need*float64(rule.Window))
+	} else {
+		//等待上一个窗口的权重降低到可以再接受一个请求
+		need := 1 - float64(rule.Limit-1-state.current)/float64(state.previous)
+		result.RetryAfter = time.Duration(need*float64(rule.Window)) - elapsed
+	}
+	result.Remaining = int(math.Max(0, math.Floor(float64(rule.Limit)-count)))
+	if state.previous > 0 {
+		result.Reset += rule.Window
+	}
+	return result
+}
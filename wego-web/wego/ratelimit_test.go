@@ -0,0 +1,92 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	var now time.Time
+	clock := func() time.Time { return now }
+	for algorithm, retryAfter := range []string{"30", "90"} {
+		now = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := NewMemoryLimitStore()
+		store.now = clock
+		r := New()
+		api := r.Group("/api")
+		api.Use(RateLimit(RateLimitConfig{Algorithm: algorithm, Limit: 2, Window: time.Minute, Store: store}))
+		api.GET("/users/:id", func(c *Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		r.GET("/public", func(c *Context) {
+			c.String(http.StatusOK, "ok")
+		})
+
+		request := func(path, ip string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.RemoteAddr = ip + ":1234"
+			r.ServeHTTP(w, req)
+			return w
+		}
+		for i, remaining := range []string{"1", "0"} {
+			if w := request("/api/users/1", "10.0.0.1"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != remaining {
+				t.Fatalf("algorithm %d request %d: unexpected response %d %v", algorithm, i, w.Code, w.Header())
+			}
+		}
+		w := request("/api/users/2", "10.0.0.1")
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != retryAfter || w.Header().Get("X-RateLimit-Limit") != "2" {
+			t.Fatalf("algorithm %d: expected 429, got %d %v", algorithm, w.Code, w.Header())
+		}
+		//不同的IP与未使用限流的组不受影响
+		if request("/api/users/1", "10.0.0.2").Code != http.StatusOK || request("/public", "10.0.0.1").Code != http.StatusOK {
+			t.Fatalf("algorithm %d: limit should be per client and per group", algorithm)
+		}
+		now = now.Add(90 * time.Second)
+		if w := request("/api/users/1", "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("algorithm %d: request should be allowed after Retry-After, got %d", algorithm, w.Code)
+		}
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	r := New()
+	r.Use(RateLimit(RateLimitConfig{Limit: 1, KeyFunc: KeyByRoute}))
+	r.GET("/users/:id", func(c *Context) {})
+	r.GET("/posts/:id", func(c *Context) {})
+
+	codes := make([]int, 0, 3)
+	for _, path := range []string{"/users/1", "/users/2", "/posts/1"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests || codes[2] != http.StatusOK {
+		t.Fatalf("requests to the same route should share the limit, got %v", codes)
+	}
+}
+
+func TestMemoryLimitStoreSharedSweep(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryLimitStore()
+	store.now = func() time.Time { return now }
+	store.lastSweep = now
+	hourly := LimitRule{Limit: 1, Window: time.Hour, Burst: 1}
+	secondly := LimitRule{Limit: 1, Window: time.Second, Burst: 1}
+	if result, _ := store.Take("hourly:10.0.0.1", hourly); !result.Allowed {
+		t.Fatal("the first request should be allowed")
+	}
+	//较短窗口的限流器触发清除时不应删除较长窗口的状态
+	now = now.Add(2 * time.Minute)
+	store.Take("secondly:10.0.0.1", secondly)
+	if result, _ := store.Take("hourly:10.0.0.1", hourly); result.Allowed {
+		t.Fatal("state of the hourly limiter should survive a sweep triggered by another limiter")
+	}
+	now = now.Add(3 * time.Hour)
+	store.Take("secondly:10.0.0.1", secondly)
+	if _, ok := store.states["hourly:10.0.0.1"]; ok {
+		t.Fatal("expired states should be removed")
+	}
+}
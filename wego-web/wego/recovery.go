@@ -1,8 +1,8 @@
 package wego
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"runtime"
 	"strings"
@@ -23,13 +23,19 @@ func trace(message string) string {
 	return str.String()
 }
 
+//Recovery 恢复处理器的panic,记录调用堆栈,并以状态码为500的 Problem 调用 c.Error
+//	外层有 ErrorHandler 时由其返回错误响应,否则返回 {"message":"Internal Server Error"}
 func Recovery() HandlerFunc {
 	return func(c *Context) {
 		defer func() {
 			if err := recover(); err != nil {
 				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				c.Fail(http.StatusInternalServerError, "Internal Server Error")
+				c.engine.Log.Errorf("%s", trace(message))
+				//panic的信息只记录在日志中,不出现在响应里
+				c.AbortWithError(&Problem{Status: http.StatusInternalServerError, Err: errors.New("panic: " + message)})
+				if !c.errorHandled && !c.Writer.Written() {
+					c.JSON(http.StatusInternalServerError, H{"message": http.StatusText(http.StatusInternalServerError)})
+				}
 			}
 		}()
 		c.Next()
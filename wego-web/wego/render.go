@@ -0,0 +1,230 @@
+package wego
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+)
+
+//常用的MIME类型
+const (
+	MIMEJSON     = "application/json"
+	MIMEHTML     = "text/html"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEPlain    = "text/plain"
+	MIMEYAML     = "application/x-yaml"
+	MIMEProtoBuf = "application/x-protobuf"
+)
+
+//Render 将数据编码后写入响应
+//	Render 在编码成功后才会写入响应头与响应体,编码失败时返回错误且不写入任何内容,
+//	因此调用者仍然可以返回500等错误响应
+type Render interface {
+	//Render 编码数据并写入w
+	Render(w http.ResponseWriter) error
+	//WriteContentType 设定响应的Content-Type
+	WriteContentType(w http.ResponseWriter)
+}
+
+//writeContentType 在未设定Content-Type时设定
+func writeContentType(w http.ResponseWriter, value string) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", value)
+	}
+}
+
+//writeRendered 设定Content-Type后写入编码完成的数据
+func writeRendered(w http.ResponseWriter, r Render, data []byte) error {
+	r.WriteContentType(w)
+	_, err := w.Write(data)
+	return err
+}
+
+//JSONRender 以json格式返回数据
+type JSONRender struct {
+	Data interface{}
+}
+
+func (r JSONRender) Render(w http.ResponseWriter) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(r.Data); err != nil {
+		return err
+	}
+	return writeRendered(w, r, buf.Bytes())
+}
+
+func (r JSONRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON+"; charset=utf-8")
+}
+
+//IndentedJSONRender 以缩进后的json格式返回数据,便于阅读
+type IndentedJSONRender struct {
+	Data interface{}
+}
+
+func (r IndentedJSONRender) Render(w http.ResponseWriter) error {
+	data, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writeRendered(w, r, data)
+}
+
+func (r IndentedJSONRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON+"; charset=utf-8")
+}
+
+//SecureJSONRender 返回json数组时在前面加上Prefix,防止json劫持
+type SecureJSONRender struct {
+	Prefix string
+	Data   interface{}
+}
+
+func (r SecureJSONRender) Render(w http.ResponseWriter) error {
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(data, []byte("[")) && bytes.HasSuffix(data, []byte("]")) {
+		data = append([]byte(r.Prefix), data...)
+	}
+	return writeRendered(w, r, data)
+}
+
+func (r SecureJSONRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON+"; charset=utf-8")
+}
+
+//jsonpCallback 为允许的回调函数名,只能是标识符或以 . 连接的标识符,如 jQuery123.cb
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*(?:\.[A-Za-z_$][0-9A-Za-z_$]*)*$`)
+
+//ValidJSONPCallback 判断callback是否可以作为jsonp的回调函数名
+func ValidJSONPCallback(callback string) bool {
+	return len(callback) <= 128 && jsonpCallback.MatchString(callback)
+}
+
+//JSONPRender 返回 callback(json); 格式的javascript,Callback为空时返回普通的json
+//	Callback不是合法的回调函数名时返回错误,防止注入任意的javascript
+type JSONPRender struct {
+	Callback string
+	Data     interface{}
+}
+
+func (r JSONPRender) Render(w http.ResponseWriter) error {
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	if r.Callback == "" {
+		return writeRendered(w, r, data)
+	}
+	if !ValidJSONPCallback(r.Callback) {
+		return fmt.Errorf("wego: invalid jsonp callback %q", r.Callback)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(r.Callback)
+	buf.WriteByte('(')
+	buf.Write(data)
+	buf.WriteString(");")
+	return writeRendered(w, r, buf.Bytes())
+}
+
+func (r JSONPRender) WriteContentType(w http.ResponseWriter) {
+	if r.Callback == "" {
+		writeContentType(w, MIMEJSON+"; charset=utf-8")
+		return
+	}
+	writeContentType(w, "application/javascript; charset=utf-8")
+}
+
+//AsciiJSONRender 返回只包含ASCII字符的json,非ASCII字符被转义为\uXXXX
+type AsciiJSONRender struct {
+	Data interface{}
+}
+
+func (r AsciiJSONRender) Render(w http.ResponseWriter) error {
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, ch := range string(data) {
+		if ch < utf8.RuneSelf {
+			buf.WriteByte(byte(ch))
+		} else if ch > 0xFFFF {
+			//超出基本平面的字符使用UTF-16代理对表示
+			ch -= 0x10000
+			fmt.Fprintf(&buf, "\\u%04x\\u%04x", 0xD800+(ch>>10), 0xDC00+(ch&0x3FF))
+		} else {
+			fmt.Fprintf(&buf, "\\u%04x", ch)
+		}
+	}
+	return writeRendered(w, r, buf.Bytes())
+}
+
+func (r AsciiJSONRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEJSON)
+}
+
+//XMLRender 以xml格式返回数据
+type XMLRender struct {
+	Data interface{}
+}
+
+func (r XMLRender) Render(w http.ResponseWriter) error {
+	data, err := xml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	return writeRendered(w, r, data)
+}
+
+func (r XMLRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEXML+"; charset=utf-8")
+}
+
+//YAMLRender 以yaml格式返回数据
+type YAMLRender struct {
+	Data interface{}
+}
+
+func (r YAMLRender) Render(w http.ResponseWriter) error {
+	data, err := marshalYAML(r.Data)
+	if err != nil {
+		return err
+	}
+	return writeRendered(w, r, data)
+}
+
+func (r YAMLRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEYAML+"; charset=utf-8")
+}
+
+//ProtoBufRender 以protobuf格式返回数据,Data必须为proto.Message
+type ProtoBufRender struct {
+	Data interface{}
+}
+
+func (r ProtoBufRender) Render(w http.ResponseWriter) error {
+	msg, ok := r.Data.(proto.Message)
+	if !ok {
+		return fmt.Errorf("wego: %T is not a proto.Message", r.Data)
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return writeRendered(w, r, data)
+}
+
+func (r ProtoBufRender) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, MIMEProtoBuf)
+}
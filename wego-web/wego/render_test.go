@@ -0,0 +1,102 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offered := []string{MIMEJSON, MIMEXML, MIMEYAML}
+	for _, tc := range []struct {
+		accept string
+		want   string
+	}{
+		{"", MIMEJSON},
+		{"application/xml", MIMEXML},
+		{"text/html, application/x-yaml;q=0.9, */*;q=0.1", MIMEYAML},
+		{"application/json;q=0.5, application/xml", MIMEXML},
+		{"application/*;q=0.8, application/yaml;q=0.8, application/x-yaml;q=0.8", MIMEYAML},
+		{"application/json;q=0, */*", MIMEXML},
+		{"text/html", ""},
+	} {
+		c := newContext(New())
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", tc.accept)
+		c.reset(httptest.NewRecorder(), req)
+		if got := c.NegotiateFormat(offered...); got != tc.want {
+			t.Fatalf("Accept %q: expected %q, got %q", tc.accept, tc.want, got)
+		}
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	type user struct {
+		Name string   `json:"name" xml:"name"`
+		Tags []string `json:"tags" xml:"tag" yaml:"tags"`
+	}
+	data := user{Name: "geektutu", Tags: []string{"go", "true"}}
+
+	r := New()
+	r.GET("/negotiate", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{Offered: []string{MIMEJSON, MIMEXML, MIMEYAML}, Data: data})
+	})
+	r.GET("/secure", func(c *Context) {
+		c.SecureJSON(http.StatusOK, []int{1, 2})
+	})
+	r.GET("/jsonp", func(c *Context) {
+		c.JSONP(http.StatusOK, H{"a": 1})
+	})
+	r.GET("/ascii", func(c *Context) {
+		c.AsciiJSON(http.StatusOK, "极客🐹")
+	})
+	r.GET("/broken", func(c *Context) {
+		c.JSON(http.StatusOK, H{"ch": make(chan int)})
+	})
+
+	for _, tc := range []struct {
+		path, accept string
+		code         int
+		contentType  string
+		body         string
+	}{
+		{"/negotiate", "application/x-yaml", http.StatusOK, MIMEYAML + "; charset=utf-8",
+			"name: geektutu\ntags:\n- go\n- \"true\"\n"},
+		{"/negotiate", "text/xml, application/xml;q=0.9", http.StatusOK, MIMEXML + "; charset=utf-8",
+			"<user><name>geektutu</name><tag>go</tag><tag>true</tag></user>"},
+		{"/negotiate", "text/html", http.StatusNotAcceptable, MIMEJSON + "; charset=utf-8",
+			"{\"message\":\"the accepted formats are not offered by the server\"}\n"},
+		{"/secure", "", http.StatusOK, MIMEJSON + "; charset=utf-8", "while(1);[1,2]"},
+		{"/jsonp?callback=cb", "", http.StatusOK, "application/javascript; charset=utf-8", "cb({\"a\":1});"},
+		{"/jsonp?callback=jQuery_1.cb", "", http.StatusOK, "application/javascript; charset=utf-8", "jQuery_1.cb({\"a\":1});"},
+		{"/jsonp?callback=alert(1)%3Bx", "", http.StatusBadRequest, MIMEJSON + "; charset=utf-8",
+			"{\"message\":\"invalid jsonp callback\"}\n"},
+		{"/ascii", "", http.StatusOK, MIMEJSON, "\"\\u6781\\u5ba2\\ud83d\\udc39\""},
+		{"/broken", "", http.StatusInternalServerError, MIMEJSON + "; charset=utf-8",
+			"{\"message\":\"Internal Server Error\"}\n"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || w.Header().Get("Content-Type") != tc.contentType || w.Body.String() != tc.body {
+			t.Fatalf("%s %q: unexpected response %d %q %q", tc.path, tc.accept, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+}
+
+func TestQuoteYAML(t *testing.T) {
+	for _, s := range []string{"0x1F", "0o17", "1_000", "1:20", "2001-12-14", "2001-12-14t21:59:43.10-05:00", "+12", ".Inf", ".NaN", "<<", "Yes", "1e3"} {
+		if q := quoteYAML(s); q != strconv.Quote(s) {
+			t.Fatalf("%s should be quoted, got %s", s, q)
+		}
+	}
+	for _, s := range []string{"go", "geektutu", "a-b", "x1F"} {
+		if q := quoteYAML(s); q != s {
+			t.Fatalf("%s shouldn't be quoted, got %s", s, q)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package wego
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+//noWritten 响应头尚未发送时size的值
+const noWritten = -1
+
+//ResponseWriter 封装了http.ResponseWriter,记录状态码、写入的字节数以及响应头是否已发送
+//	WriteHeader 只记录状态码,直到第一次写入响应体或调用 WriteHeaderNow 时才真正发送响应头,
+//	因此在写入响应体之前仍然可以修改状态码与响应头
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+
+	//Status 返回响应的状态码
+	Status() int
+	//Size 返回已写入响应体的字节数,响应头尚未发送时为-1
+	Size() int
+	//Written 返回响应头是否已经发送
+	Written() bool
+	//WriteHeaderNow 立即发送响应头
+	WriteHeaderNow()
+	//WriteString 写入字符串
+	WriteString(s string) (int, error)
+	//Pusher 返回支持HTTP/2服务端推送的http.Pusher,不支持时返回nil
+	Pusher() http.Pusher
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+	log    *AccessLogger
+}
+
+var _ ResponseWriter = &responseWriter{}
+
+func (w *responseWriter) reset(writer http.ResponseWriter, log *AccessLogger) {
+	w.ResponseWriter = writer
+	w.log = log
+	w.status = http.StatusOK
+	w.size = noWritten
+}
+
+//Unwrap 返回原始的http.ResponseWriter,供http.ResponseController使用
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && w.status != code {
+		if w.Written() {
+			w.log.Warnf("Headers were already written. Wanted to override status code %d with %d", w.status, code)
+			return
+		}
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = w.ResponseWriter.Write(data)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) WriteString(s string) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+//Flush 发送响应头并将缓冲的数据发送给客户端
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+//Hijack 接管底层连接,之后不能再通过ResponseWriter写入响应
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("wego: the ResponseWriter doesn't support hijacking")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return h.Hijack()
+}
+
+func (w *responseWriter) Pusher() http.Pusher {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p
+	}
+	return nil
+}
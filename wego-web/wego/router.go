@@ -2,20 +2,24 @@ package wego
 
 import (
 	"net/http"
+	"path"
+	"sort"
 	"strings"
 )
 
 type router struct {
-	//roots 存储每种请求方式的Trie树根节点
+	//roots 存储每种请求方式的Radix树根节点
 	roots map[string]*node
-	//handlers 存储每种请求方式的 HandlerFunc
-	handlers map[string]HandlerFunc
+	//maxParams 所有路由中路径参数的最大个数,用于预先分配Context.Params
+	maxParams int
+	//names 存储命名路由的名称与路由所在的节点
+	names map[string]*node
 }
 
 func newRouter() *router {
 	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+		roots: make(map[string]*node),
+		names: make(map[string]*node),
 	}
 }
 
@@ -37,41 +41,40 @@ func parsePattern(pattern string) []string {
 	return parts
 }
 
-//addRoute 添加路由规则
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
-	parts := parsePattern(pattern)
-	key := method + "-" + pattern
+//addRoute 添加路由规则,返回路由所在的节点,路由模式不合法或与已有路由冲突时panic
+func (r *router) addRoute(method string, pattern string, handlers []HandlerFunc) *node {
+	if pattern == "" || pattern[0] != '/' {
+		panic("wego: route pattern must begin with '/', got '" + pattern + "'")
+	}
 	if _, ok := r.roots[method]; !ok {
-		//检查是否有method对应的子节点,若没有就创建一个
+		//检查是否有method对应的根节点,若没有就创建一个
 		r.roots[method] = &node{}
 	}
-	//在子节点上插入
-	r.roots[method].insert(pattern, parts, 0)
-	//设定处理器
-	r.handlers[key] = handler
+	//在根节点上插入
+	n, err := r.roots[method].insert(pattern, handlers)
+	if err != nil {
+		panic("wego: " + method + " " + err.Error())
+	}
+	params := 0
+	for _, part := range parsePattern(pattern) {
+		if part[0] == ':' || part[0] == '*' {
+			params++
+		}
+	}
+	if params > r.maxParams {
+		r.maxParams = params
+	}
+	return n
 }
 
 //getRoute 获取路由规则
-func (r *router) getRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
+func (r *router) getRoute(method string, path string) (*node, Params) {
 	root, ok := r.roots[method]
 	if !ok {
 		return nil, nil
 	}
-	n := root.search(searchParts, 0)
-	if n != nil {
-		parts := parsePattern(n.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
-		}
-	}
+	params := make(Params, 0, r.maxParams)
+	n := root.search(path, &params)
 	return n, params
 }
 
@@ -85,18 +88,157 @@ func (r *router) getRoutes(method string) []*node {
 	return nodes
 }
 
-func (r *router) handle(c *Context) {
-	n, params := r.getRoute(c.Method, c.Path)
-	if n != nil {
-		c.Params = params
-		key := c.Method + "-" + n.pattern
-		//将最终处理请求的handler加入c的handler列表中
-		c.handlers = append(c.handlers, r.handlers[key])
+//allowed 返回能够匹配path的所有请求方式,用于405响应与OPTIONS响应的Allow头
+//	path为 * 时返回所有已注册的请求方式
+func (r *router) allowed(path string, reqMethod string) string {
+	set := make(map[string]bool)
+	for method := range r.roots {
+		if method == reqMethod {
+			continue
+		}
+		if path != "*" {
+			if n, _ := r.getRoute(method, path); n == nil {
+				continue
+			}
+		}
+		set[method] = true
+		//GET路由同时能够响应HEAD请求
+		if method == http.MethodGet {
+			set[http.MethodHead] = true
+		}
+	}
+	if len(set) == 0 {
+		return ""
+	}
+	//OPTIONS请求总是可以被自动响应
+	set[http.MethodOptions] = true
+	methods := make([]string, 0, len(set))
+	for method := range set {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+//redirectTrailingSlash 判断path增加或去掉末尾的 / 后能否匹配到路由
+func redirectTrailingSlash(root *node, path string) (string, bool) {
+	if path == "/" {
+		return "", false
+	}
+	if strings.HasSuffix(path, "/") {
+		path = path[:len(path)-1]
 	} else {
-		c.handlers = append(c.handlers, func(context *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+		path += "/"
+	}
+	var params Params
+	if root.search(path, &params) == nil {
+		return "", false
 	}
-	//开始执行handlers
+	return path, true
+}
+
+//redirect 返回重定向到path的处理器,GET请求使用301,其他请求使用308以保留请求方式与请求体
+func redirect(path string) HandlerFunc {
+	return func(c *Context) {
+		code := http.StatusPermanentRedirect
+		if c.Method == http.MethodGet {
+			code = http.StatusMovedPermanently
+		}
+		u := *c.Req.URL
+		u.Path, u.RawPath = path, ""
+		http.Redirect(c.Writer, c.Req, u.String(), code)
+	}
+}
+
+func (r *router) handle(c *Context) {
+	engine := c.engine
+	if max := engine.MaxRequestBodySize; max > 0 && c.Req.ContentLength > max {
+		//请求体超出限制时不再匹配路由
+		c.handlers = engine.combineHandlers([]HandlerFunc{func(c *Context) {
+			c.String(http.StatusRequestEntityTooLarge, "413 REQUEST ENTITY TOO LARGE: %s\n", c.Path)
+		}})
+		c.Next()
+		return
+	}
+	root := r.roots[c.Method]
+	if root != nil {
+		if n := root.search(c.Path, &c.Params); n != nil {
+			//路由的处理链在注册时已确定
+			c.fullPath = n.pattern
+			c.handlers = n.handlers
+			c.Next()
+			return
+		}
+	}
+	if get := r.roots[http.MethodGet]; c.Method == http.MethodHead && get != nil {
+		//未注册对应的HEAD路由时使用GET路由响应,响应体由net/http丢弃
+		if n := get.search(c.Path, &c.Params); n != nil {
+			c.fullPath = n.pattern
+			c.handlers = n.handlers
+			c.Next()
+			return
+		}
+		if root == nil {
+			root = get
+		}
+	}
+	var handler HandlerFunc
+	if root != nil && c.Method != http.MethodConnect {
+		//尝试修正末尾的 / 或路径的大小写后重定向
+		if engine.RedirectTrailingSlash {
+			if fixed, ok := redirectTrailingSlash(root, c.Path); ok {
+				handler = redirect(fixed)
+			}
+		}
+		if handler == nil && engine.RedirectFixedPath {
+			if fixed, ok := root.findCaseInsensitivePath(cleanPath(c.Path), engine.RedirectTrailingSlash); ok {
+				handler = redirect(fixed)
+			}
+		}
+	}
+	handlers := []HandlerFunc{handler}
+	if handler == nil {
+		if allow := r.allowed(c.Path, c.Method); allow != "" {
+			//路径存在但请求方式不匹配
+			c.SetHeader("Allow", allow)
+			if c.Method == http.MethodOptions {
+				//未注册OPTIONS路由时自动响应
+				handlers[0] = func(c *Context) {
+					c.Status(http.StatusNoContent)
+				}
+			} else if len(engine.noMethod) > 0 {
+				c.Status(http.StatusMethodNotAllowed)
+				handlers = engine.noMethod
+			} else {
+				handlers[0] = func(c *Context) {
+					c.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s %s\n", c.Method, c.Path)
+				}
+			}
+		} else if len(engine.noRoute) > 0 {
+			c.Status(http.StatusNotFound)
+			handlers = engine.noRoute
+		} else {
+			handlers[0] = func(c *Context) {
+				c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+			}
+		}
+	}
+	//未匹配到路由时只执行全局中间件
+	c.handlers = engine.combineHandlers(handlers)
 	c.Next()
 }
+
+//cleanPath 返回规范化后的路径: 去除多余的 / 以及 . 与 .. ,保留末尾的 /
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
@@ -2,7 +2,10 @@ package wego
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -34,22 +37,22 @@ func TestGetRoute(t *testing.T) {
 	if n.pattern != "/hello/:name" {
 		t.Fatal("should match /hello/:name")
 	}
-	if ps["name"] != "wego" {
+	if ps.ByName("name") != "wego" {
 		t.Fatal("name should be equal to 'wego'")
 	}
-	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["name"])
+	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps.ByName("name"))
 
 }
 
 func TestGetRoute2(t *testing.T) {
 	r := newTestRouter()
 	n1, ps1 := r.getRoute("GET", "/assets/file1.txt")
-	ok1 := n1.pattern == "/assets/*filepath" && ps1["filepath"] == "file1.txt"
+	ok1 := n1.pattern == "/assets/*filepath" && ps1.ByName("filepath") == "file1.txt"
 	if !ok1 {
 		t.Fatal("pattern should be /assets/*filepath & filepath should be file1.txt")
 	}
 	n2, ps2 := r.getRoute("GET", "/assets/css/test.css")
-	ok2 := n2.pattern == "/assets/*filepath" && ps2["filepath"] == "css/test.css"
+	ok2 := n2.pattern == "/assets/*filepath" && ps2.ByName("filepath") == "css/test.css"
 	if !ok2 {
 		t.Fatal("pattern should be /assets/*filepath & filepath should be css/test.css")
 	}
@@ -67,3 +70,192 @@ func TestGetRoutes(t *testing.T) {
 		t.Fatal("the number of routes should be 5")
 	}
 }
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.GET("/users/:id", func(c *Context) { c.String(http.StatusOK, "get %s", c.Param("id")) })
+	r.PUT("/users/:id", func(c *Context) { c.String(http.StatusOK, "put %s", c.Param("id")) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/1", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status should be 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, PUT" {
+		t.Fatalf("unexpected Allow header: %q", allow)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/posts/1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status should be 404, got %d", w.Code)
+	}
+}
+
+func TestAutoHeadAndOptions(t *testing.T) {
+	r := New()
+	r.GET("/hello", func(c *Context) { c.String(http.StatusOK, "hello") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/hello", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("HEAD should be answered by GET route, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/hello", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Fatalf("OPTIONS should be answered automatically, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestRoutePriority(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/users/new", nil)
+	r.addRoute("GET", "/users/:id", nil)
+	r.addRoute("GET", "/users/:id/posts", nil)
+	r.addRoute("GET", "/users/*path", nil)
+	r.addRoute("GET", "/userinfo", nil)
+
+	cases := []struct{ path, pattern, params string }{
+		{"/users/new", "/users/new", ""},
+		{"/users/42", "/users/:id", "id=42"},
+		{"/users/new/posts", "/users/:id/posts", "id=new"},
+		{"/users/42/friends", "/users/*path", "path=42/friends"},
+		{"/userinfo", "/userinfo", ""},
+	}
+	for _, tc := range cases {
+		n, ps := r.getRoute("GET", tc.path)
+		if n == nil || n.pattern != tc.pattern {
+			t.Fatalf("%s should match %s, got %v", tc.path, tc.pattern, n)
+		}
+		var got []string
+		for _, p := range ps {
+			got = append(got, p.Key+"="+p.Value)
+		}
+		if strings.Join(got, ",") != tc.params {
+			t.Fatalf("%s: params should be %q, got %v", tc.path, tc.params, got)
+		}
+	}
+	if n, _ := r.getRoute("GET", "/users"); n != nil {
+		t.Fatalf("/users shouldn't be matched, got %v", n)
+	}
+}
+
+func TestRouteConflict(t *testing.T) {
+	conflicts := [][]string{
+		{"/users/:id", "/users/:name"},
+		{"/users/:id", "/users/:id"},
+		{"/files/*path", "/files/*name"},
+		{"/files/*path/x"},
+		{"/users/:"},
+		{"/users/:id:name"},
+		{"users"},
+	}
+	for _, patterns := range conflicts {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("registering %v should panic", patterns)
+				}
+			}()
+			r := newRouter()
+			for _, pattern := range patterns {
+				r.addRoute("GET", pattern, nil)
+			}
+		}()
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+	r.GET("/users/:id/", func(c *Context) {})
+	r.GET("/About", func(c *Context) {})
+	r.POST("/submit", func(c *Context) {})
+
+	cases := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{http.MethodGet, "/users/1", http.StatusMovedPermanently, "/users/1/"},
+		{http.MethodGet, "/About/?a=b", http.StatusMovedPermanently, "/About?a=b"},
+		{http.MethodGet, "/about", http.StatusMovedPermanently, "/About"},
+		{http.MethodGet, "/x/../ABOUT/", http.StatusMovedPermanently, "/About"},
+		{http.MethodPost, "/submit/", http.StatusPermanentRedirect, "/submit"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.code || w.Header().Get("Location") != tc.location {
+			t.Fatalf("%s %s should redirect to %s with %d, got %d %s",
+				tc.method, tc.path, tc.location, tc.code, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestSearchWithoutAllocation(t *testing.T) {
+	r := newTestRouter()
+	root := r.roots["GET"]
+	params := make(Params, 0, r.maxParams)
+	allocs := testing.AllocsPerRun(100, func() {
+		params = params[:0]
+		root.search("/hello/wego", &params)
+		params = params[:0]
+		root.search("/assets/css/test.css", &params)
+	})
+	if allocs != 0 {
+		t.Fatalf("search should not allocate, got %v allocs", allocs)
+	}
+}
+
+func TestParamConstraint(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/users/:id<int>", nil)
+	r.addRoute("GET", "/users/:uid<uuid>", nil)
+	r.addRoute("GET", "/users/:name", nil)
+	r.addRoute("GET", "/files/:name<[a-z0-9-]+>", nil)
+	r.addRoute("GET", "/v/:id<uint>/raw", nil)
+
+	cases := []struct{ path, pattern, key, value string }{
+		{"/users/42", "/users/:id<int>", "id", "42"},
+		{"/users/0F6A2C3E-1111-4a4b-9c9d-0123456789ab", "/users/:uid<uuid>", "uid", "0F6A2C3E-1111-4a4b-9c9d-0123456789ab"},
+		{"/users/abc", "/users/:name", "name", "abc"},
+		{"/files/a-b-1", "/files/:name<[a-z0-9-]+>", "name", "a-b-1"},
+		{"/v/7/raw", "/v/:id<uint>/raw", "id", "7"},
+	}
+	for _, tc := range cases {
+		n, ps := r.getRoute("GET", tc.path)
+		if n == nil || n.pattern != tc.pattern || ps.ByName(tc.key) != tc.value {
+			t.Fatalf("%s should match %s with %s=%s, got %v %v", tc.path, tc.pattern, tc.key, tc.value, n, ps)
+		}
+	}
+	for _, path := range []string{"/files/A_B", "/v/-1/raw", "/v/x/raw"} {
+		if n, _ := r.getRoute("GET", path); n != nil {
+			t.Fatalf("%s shouldn't be matched, got %v", path, n)
+		}
+	}
+}
+
+func TestParamConstraintConflict(t *testing.T) {
+	conflicts := [][]string{
+		{"/users/:id<int>", "/users/:uid<int>"},
+		{"/users/:id<[0-9>"},
+		{"/users/:id<int"},
+		{"/files/*path<int>"},
+	}
+	for _, patterns := range conflicts {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("registering %v should panic", patterns)
+				}
+			}()
+			r := newRouter()
+			for _, pattern := range patterns {
+				r.addRoute("GET", pattern, nil)
+			}
+		}()
+	}
+}
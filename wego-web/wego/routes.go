@@ -0,0 +1,146 @@
+package wego
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+//Route 为注册的路由,使用 Any 注册时包含所有请求方式的路由
+type Route struct {
+	engine *Engine
+	nodes  []*node
+}
+
+//route 使用注册得到的节点创建Route
+func (group *RouterGroup) route(nodes ...*node) *Route {
+	return &Route{engine: group.engine, nodes: nodes}
+}
+
+//Name 为路由命名,之后可以通过 Engine.URL 使用名称生成路由的url,名称重复时panic
+//	e.GET("/users/:id", showUser).Name("user.show")
+func (r *Route) Name(name string) *Route {
+	names := r.engine.router.names
+	if n, ok := names[name]; ok {
+		panic(fmt.Sprintf("wego: route name '%s' is already used by '%s'", name, n.pattern))
+	}
+	names[name] = r.nodes[0]
+	for _, n := range r.nodes {
+		n.name = name
+	}
+	return r
+}
+
+//RouteInfo 描述一条已注册的路由
+type RouteInfo struct {
+	Method      string //请求方式
+	Path        string //路由模式
+	Name        string //路由名称,未命名时为空
+	Handler     string //最终处理请求的函数名
+	Middlewares int    //处理函数之前的中间件个数
+}
+
+//Routes 返回所有已注册的路由,按请求方式与路由模式排序
+func (engine *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0)
+	for method, root := range engine.router.roots {
+		nodes := make([]*node, 0)
+		root.travel(&nodes)
+		for _, n := range nodes {
+			info := RouteInfo{Method: method, Path: n.pattern, Name: n.name}
+			if len(n.handlers) > 0 {
+				info.Handler = nameOfFunction(n.handlers[len(n.handlers)-1])
+				info.Middlewares = len(n.handlers) - 1
+			}
+			routes = append(routes, info)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Path < routes[j].Path
+	})
+	return routes
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+//URL 使用命名路由生成url,pairs为交替出现的参数名与参数值
+//	e.URL("user.show", "id", 42) => /users/42
+//	参数值会被转义,缺少参数、参数值为空或不满足约束时返回错误,路由模式中未出现的参数作为url中的查询参数
+func (engine *Engine) URL(name string, pairs ...interface{}) (string, error) {
+	route, ok := engine.router.names[name]
+	if !ok {
+		return "", fmt.Errorf("wego: route named '%s' not found", name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("wego: odd number of params for route '%s'", name)
+	}
+	params := make(map[string]string, len(pairs)/2)
+	keys := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return "", fmt.Errorf("wego: param name of route '%s' must be string, got %T", name, pairs[i])
+		}
+		if _, dup := params[key]; !dup {
+			keys = append(keys, key)
+		}
+		params[key] = fmt.Sprint(pairs[i+1])
+	}
+
+	var sb strings.Builder
+	path := route.pattern
+	for {
+		i, end := wildcardSpan(path)
+		if i < 0 {
+			sb.WriteString(path)
+			break
+		}
+		sb.WriteString(path[:i])
+		wild := path[i:end]
+		key, constraint, _ := parseWildcard(wild)
+		value, ok := params[key]
+		if !ok && wild[0] == ':' {
+			return "", fmt.Errorf("wego: missing param '%s' for route '%s'", key, name)
+		}
+		if value == "" && wild[0] == ':' {
+			//参数不能匹配空的路径片段
+			return "", fmt.Errorf("wego: empty param '%s' for route '%s'", key, name)
+		}
+		delete(params, key)
+		if wild[0] == '*' {
+			//通配参数可以包含 / ,逐段转义
+			segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for j, segment := range segments {
+				segments[j] = url.PathEscape(segment)
+			}
+			sb.WriteString(strings.Join(segments, "/"))
+		} else {
+			if match := route.matchers[key]; match != nil {
+				if !match(value) {
+					return "", fmt.Errorf("wego: param '%s'=%q doesn't match <%s> for route '%s'", key, value, constraint, name)
+				}
+			}
+			sb.WriteString(url.PathEscape(value))
+		}
+		path = path[end:]
+	}
+	if len(params) > 0 {
+		query := url.Values{}
+		for _, key := range keys {
+			if value, ok := params[key]; ok {
+				query.Set(key, value)
+			}
+		}
+		sb.WriteString("?")
+		sb.WriteString(query.Encode())
+	}
+	return sb.String(), nil
+}
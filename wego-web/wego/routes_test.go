@@ -0,0 +1,68 @@
+package wego
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func showUser(c *Context) {}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) { c.Next() })
+	r.GET("/users/:id", showUser).Name("user.show")
+	r.POST("/users", func(c *Context) {})
+
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("the number of routes should be 2, got %d", len(routes))
+	}
+	get := routes[0]
+	if get.Method != "GET" || get.Path != "/users/:id" || get.Name != "user.show" ||
+		get.Handler != "wego.showUser" || get.Middlewares != 1 {
+		t.Fatalf("unexpected route info: %+v", get)
+	}
+	if routes[1].Method != "POST" || routes[1].Name != "" {
+		t.Fatalf("unexpected route info: %+v", routes[1])
+	}
+}
+
+func TestURL(t *testing.T) {
+	r := New()
+	v1 := r.Group("/v1")
+	v1.GET("/users/:id<int>/files/*path", showUser).Name("user.file")
+	v1.GET("/search/:q", showUser).Name("search")
+
+	cases := []struct {
+		name  string
+		pairs []interface{}
+		url   string
+	}{
+		{"user.file", []interface{}{"id", 42, "path", "a b/c.txt"}, "/v1/users/42/files/a%20b/c.txt"},
+		{"search", []interface{}{"q", "x/y", "page", 2}, "/v1/search/x%2Fy?page=2"},
+	}
+	for _, tc := range cases {
+		u, err := r.URL(tc.name, tc.pairs...)
+		if err != nil || u != tc.url {
+			t.Fatalf("%s should be %s, got %s %v", tc.name, tc.url, u, err)
+		}
+	}
+	for _, pairs := range [][]interface{}{{"path", "a"}, {"id", "abc", "path", "a"}, {"id"}} {
+		if _, err := r.URL("user.file", pairs...); err == nil {
+			t.Fatalf("URL with %v should fail", pairs)
+		}
+	}
+	if _, err := r.URL("search", "q", ""); err == nil {
+		t.Fatal("URL with an empty param should fail")
+	}
+	if _, err := r.URL("unknown"); err == nil {
+		t.Fatal("URL of unknown route should fail")
+	}
+
+	tmpl := template.Must(template.New("").Funcs(r.templateFuncs()).Parse(`{{ url "search" "q" . }}`))
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, "wego"); err != nil || sb.String() != "/v1/search/wego" {
+		t.Fatalf("url func in template failed: %s %v", sb.String(), err)
+	}
+}
@@ -0,0 +1,90 @@
+package wego
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//nonceToken 为ContentSecurityPolicy中被替换为本次请求的nonce的占位符
+const nonceToken = "{nonce}"
+
+//SecureConfig 为 Secure 中间件的设定,字段为空时不设定对应的响应头
+type SecureConfig struct {
+	//HSTSMaxAge 为 Strict-Transport-Security 的max-age,为0时不设定
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	//ContentSecurityPolicy 中的 {nonce} 会被替换为每个请求不同的随机值,使用 c.CSPNonce() 获取
+	//	如 script-src 'self' 'nonce-{nonce}'
+	ContentSecurityPolicy string
+	FrameOptions          string //X-Frame-Options,如 DENY、SAMEORIGIN
+	ReferrerPolicy        string //Referrer-Policy
+	ContentTypeNosniff    bool   //设定 X-Content-Type-Options: nosniff
+}
+
+//DefaultSecureConfig 返回推荐的设定,可以在此基础上修改
+func DefaultSecureConfig() SecureConfig {
+	return SecureConfig{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'; object-src 'none'; base-uri 'self'",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		ContentTypeNosniff:    true,
+	}
+}
+
+//Secure 设定安全相关的响应头
+func Secure(config SecureConfig) HandlerFunc {
+	var hsts string
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(config.HSTSMaxAge/time.Second), 10)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	useNonce := strings.Contains(config.ContentSecurityPolicy, nonceToken)
+	return func(c *Context) {
+		header := c.Writer.Header()
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if config.ContentSecurityPolicy != "" {
+			csp := config.ContentSecurityPolicy
+			if useNonce {
+				c.cspNonce = newCSPNonce()
+				csp = strings.ReplaceAll(csp, nonceToken, c.cspNonce)
+			}
+			header.Set("Content-Security-Policy", csp)
+		}
+		if config.FrameOptions != "" {
+			header.Set("X-Frame-Options", config.FrameOptions)
+		}
+		if config.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", config.ReferrerPolicy)
+		}
+		if config.ContentTypeNosniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+		c.Next()
+	}
+}
+
+func newCSPNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+//CSPNonce 返回本次请求的Content-Security-Policy nonce,在模板中用于 <script nonce="...">
+func (c *Context) CSPNonce() string {
+	return c.cspNonce
+}
@@ -0,0 +1,33 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecure(t *testing.T) {
+	r := New()
+	r.Use(Secure(DefaultSecureConfig()))
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, c.CSPNonce())
+	})
+
+	nonces := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		header, nonce := w.Header(), w.Body.String()
+		if nonce == "" || nonces[nonce] {
+			t.Fatalf("nonce should be unique for each request, got %q", nonce)
+		}
+		nonces[nonce] = true
+		if header.Get("Content-Security-Policy") != "default-src 'self'; script-src 'self' 'nonce-"+nonce+"'; object-src 'none'; base-uri 'self'" ||
+			header.Get("Strict-Transport-Security") != "max-age=31536000; includeSubDomains" ||
+			header.Get("X-Frame-Options") != "DENY" ||
+			header.Get("Referrer-Policy") != "strict-origin-when-cross-origin" ||
+			header.Get("X-Content-Type-Options") != "nosniff" {
+			t.Fatalf("unexpected security headers %v", header)
+		}
+	}
+}
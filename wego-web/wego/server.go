@@ -0,0 +1,182 @@
+package wego
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//serverState 记录engine启动的服务器,用于优雅关闭
+type serverState struct {
+	mu       sync.Mutex
+	servers  map[*http.Server]struct{}
+	hooks    []func(ctx context.Context) error
+	shutdown bool
+	done     chan struct{} //Shutdown 执行完毕后关闭
+}
+
+//doneChan 返回Shutdown完成时关闭的channel,调用时需持有mu
+func (s *serverState) doneChan() chan struct{} {
+	if s.done == nil {
+		s.done = make(chan struct{})
+	}
+	return s.done
+}
+
+//newServer 使用engine的配置创建http.Server
+func (engine *Engine) newServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		ReadTimeout:       engine.ReadTimeout,
+		ReadHeaderTimeout: engine.ReadHeaderTimeout,
+		WriteTimeout:      engine.WriteTimeout,
+		IdleTimeout:       engine.IdleTimeout,
+		MaxHeaderBytes:    engine.MaxHeaderBytes,
+	}
+}
+
+//serve 记录srv并调用serveFunc启动服务
+//	服务因 Shutdown 而停止时,等待 Shutdown 完成(处理中的请求结束、钩子执行完毕)后返回nil
+func (engine *Engine) serve(srv *http.Server, serveFunc func() error) error {
+	s := &engine.state
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	if s.servers == nil {
+		s.servers = make(map[*http.Server]struct{})
+	}
+	s.servers[srv] = struct{}{}
+	done := s.doneChan()
+	s.mu.Unlock()
+
+	err := serveFunc()
+	if err == http.ErrServerClosed {
+		<-done
+		return nil
+	}
+	s.mu.Lock()
+	delete(s.servers, srv)
+	s.mu.Unlock()
+	return err
+}
+
+//Run 在addr上启动HTTP服务
+func (engine *Engine) Run(addr string) (err error) {
+	engine.Log.Printf("Listening and serving HTTP on %s", addr)
+	srv := engine.newServer(addr)
+	return engine.serve(srv, srv.ListenAndServe)
+}
+
+//RunTLS 在addr上启动HTTPS服务
+func (engine *Engine) RunTLS(addr string, certFile string, keyFile string) (err error) {
+	engine.Log.Printf("Listening and serving HTTPS on %s", addr)
+	srv := engine.newServer(addr)
+	return engine.serve(srv, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+//RunUnix 在Unix域套接字file上启动HTTP服务,服务停止后删除套接字文件
+func (engine *Engine) RunUnix(file string) (err error) {
+	engine.Log.Printf("Listening and serving HTTP on unix:%s", file)
+	listener, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	return engine.RunListener(listener)
+}
+
+//RunListener 使用已创建的listener启动HTTP服务,可用于systemd socket激活等场景
+func (engine *Engine) RunListener(listener net.Listener) (err error) {
+	engine.Log.Printf("Listening and serving HTTP on listener %s", listener.Addr())
+	srv := engine.newServer(listener.Addr().String())
+	return engine.serve(srv, func() error {
+		return srv.Serve(listener)
+	})
+}
+
+//OnShutdown 注册在 Shutdown 时执行的钩子,例如关闭数据库连接池、从服务发现中注销
+//	钩子在所有处理中的请求结束后按注册顺序执行,ctx为传入 Shutdown 的ctx
+func (engine *Engine) OnShutdown(hooks ...func(ctx context.Context) error) {
+	s := &engine.state
+	s.mu.Lock()
+	s.hooks = append(s.hooks, hooks...)
+	s.mu.Unlock()
+}
+
+//Shutdown 优雅关闭engine启动的所有服务器
+//	先停止接收新的连接,再等待处理中的请求结束,最后执行 OnShutdown 注册的钩子
+//	ctx到期时不再等待处理中的请求,返回ctx的错误,钩子仍然会被执行
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	s := &engine.state
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return nil
+	}
+	s.shutdown = true
+	servers := make([]*http.Server, 0, len(s.servers))
+	for srv := range s.servers {
+		servers = append(servers, srv)
+	}
+	hooks := s.hooks
+	done := s.doneChan()
+	s.mu.Unlock()
+	defer close(done)
+
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			errs <- srv.Shutdown(ctx)
+		}(srv)
+	}
+	var firstErr error
+	for range servers {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			engine.Log.Errorf("Shutdown hook failed: %v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+//RunGraceful 在addr上启动HTTP服务,收到SIGINT或SIGTERM后调用 Shutdown 优雅关闭
+//	timeout 为等待处理中的请求结束的最长时间
+func (engine *Engine) RunGraceful(addr string, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- engine.Run(addr)
+	}()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case err := <-errs:
+		//服务启动失败
+		return err
+	case sig := <-quit:
+		engine.Log.Printf("Received signal %s, shutting down", sig)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := engine.Shutdown(ctx); err != nil {
+		return err
+	}
+	return <-errs
+}
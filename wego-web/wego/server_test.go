@@ -0,0 +1,65 @@
+package wego
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	r := New()
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+	hooked := false
+	r.OnShutdown(func(ctx context.Context) error {
+		hooked = true
+		return nil
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- r.RunListener(listener)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-body; got != "done" {
+		t.Fatalf("in-flight request should be drained, got %q", got)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("RunListener should return nil after Shutdown, got %v", err)
+	}
+	if !hooked {
+		t.Fatal("shutdown hook should be executed")
+	}
+	if err := r.Run("127.0.0.1:0"); err != http.ErrServerClosed {
+		t.Fatalf("Run after Shutdown should return ErrServerClosed, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package wego
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//flashKey 为Flash消息在会话中的键
+const flashKey = "_flash"
+
+func init() {
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+//SessionOptions 为 Sessions 中间件的设定,零值字段使用默认值
+type SessionOptions struct {
+	CookieName string        //cookie名称,默认为 wego_session
+	Path       string        //cookie路径,默认为 /
+	Domain     string        //cookie域名
+	Secure     bool          //只通过https发送cookie
+	SameSite   http.SameSite //默认为 http.SameSiteLaxMode
+
+	//IdleTimeout 为会话的空闲过期时间,超过该时间未访问的会话失效,默认30分钟
+	IdleTimeout time.Duration
+	//AbsoluteTimeout 为会话从创建起的最长有效时间,即使一直在访问也会失效,默认24小时
+	AbsoluteTimeout time.Duration
+}
+
+//Sessions 从cookie中加载会话,处理器中使用 c.Session() 读写会话
+//	会话在修改后需要调用 Session.Save 才会被保存,Save必须在写入响应体之前调用
+func Sessions(store Store, options SessionOptions) HandlerFunc {
+	if options.CookieName == "" {
+		options.CookieName = "wego_session"
+	}
+	if options.Path == "" {
+		options.Path = "/"
+	}
+	if options.SameSite == 0 {
+		options.SameSite = http.SameSiteLaxMode
+	}
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = 30 * time.Minute
+	}
+	if options.AbsoluteTimeout <= 0 {
+		options.AbsoluteTimeout = 24 * time.Hour
+	}
+	return func(c *Context) {
+		s := loadSession(c, store, &options)
+		c.session = s
+		//空闲一段时间后刷新访问时间,延长空闲过期时间
+		if !s.isNew && time.Since(s.accessed) > options.IdleTimeout/10 {
+			if err := s.Save(); err != nil {
+				c.Fail(http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+//Session 返回 Sessions 中间件加载的会话,未使用该中间件时panic
+func (c *Context) Session() *Session {
+	if c.session == nil {
+		panic("wego: Sessions middleware is not used")
+	}
+	return c.session
+}
+
+//Session 为一个用户会话
+//	Save等方法通过c写入cookie,Context的副本使用绑定到副本的Session,会话中的值也是单独的副本
+type Session struct {
+	c       *Context
+	store   Store
+	options *SessionOptions
+
+	id       string
+	values   map[string]interface{}
+	created  time.Time
+	accessed time.Time
+	cookie   string //请求中的cookie值,为空表示新会话
+	isNew    bool
+}
+
+//sessionRecord 为保存到Store中的会话数据
+type sessionRecord struct {
+	ID       string
+	Values   map[string]interface{}
+	Created  time.Time
+	Accessed time.Time
+}
+
+func loadSession(c *Context, store Store, options *SessionOptions) *Session {
+	s := &Session{c: c, store: store, options: options}
+	if cookie, err := c.Cookie(options.CookieName); err == nil && cookie.Value != "" {
+		s.cookie = cookie.Value
+		data, err := store.Load(cookie.Value)
+		var record sessionRecord
+		if err == nil && data != nil && gob.NewDecoder(bytes.NewReader(data)).Decode(&record) == nil {
+			now := time.Now()
+			if now.Sub(record.Accessed) < options.IdleTimeout && now.Sub(record.Created) < options.AbsoluteTimeout {
+				s.id, s.values, s.created, s.accessed = record.ID, record.Values, record.Created, record.Accessed
+				if s.values == nil {
+					s.values = make(map[string]interface{})
+				}
+				return s
+			}
+			//已过期的会话不再使用
+			store.Delete(cookie.Value)
+		}
+	}
+	s.reset()
+	return s
+}
+
+//bind 返回绑定到c的s的副本,会话中的值被复制,使副本可以在其他goroutine中修改,s为nil时返回nil
+func (s *Session) bind(c *Context) *Session {
+	if s == nil {
+		return nil
+	}
+	cp := *s
+	cp.c = c
+	cp.values = make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		cp.values[k] = v
+	}
+	return &cp
+}
+
+//reset 将s重置为一个新的空会话
+func (s *Session) reset() {
+	s.id = newSessionID()
+	s.values = make(map[string]interface{})
+	s.created = time.Now()
+	s.accessed = s.created
+	s.isNew = true
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+//ID 返回会话id
+func (s *Session) ID() string {
+	return s.id
+}
+
+//IsNew 返回会话是否为本次请求新建的
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+//Get 返回key对应的值,不存在时返回nil
+func (s *Session) Get(key string) interface{} {
+	return s.values[key]
+}
+
+//Set 设定key对应的值,值的类型不是基本类型时需要先使用gob.Register注册
+func (s *Session) Set(key string, value interface{}) {
+	s.values[key] = value
+}
+
+//Delete 删除key对应的值
+func (s *Session) Delete(key string) {
+	delete(s.values, key)
+}
+
+//Clear 删除会话中所有的值
+func (s *Session) Clear() {
+	s.values = make(map[string]interface{})
+}
+
+//Flash 添加一条只读取一次的消息,常用于重定向后显示提示
+func (s *Session) Flash(value interface{}) {
+	flashes, _ := s.values[flashKey].([]interface{})
+	s.values[flashKey] = append(flashes, value)
+}
+
+//Flashes 返回并删除所有的Flash消息,需要调用Save才会从Store中删除
+func (s *Session) Flashes() []interface{} {
+	flashes, _ := s.values[flashKey].([]interface{})
+	delete(s.values, flashKey)
+	return flashes
+}
+
+//Save 保存会话并设定cookie,必须在写入响应体之前调用
+func (s *Session) Save() error {
+	if s.c.Writer.Written() {
+		return errors.New("wego: session: can't save after the response is written")
+	}
+	now := time.Now()
+	s.accessed = now
+	ttl := s.options.IdleTimeout
+	if remaining := s.created.Add(s.options.AbsoluteTimeout).Sub(now); remaining < ttl {
+		ttl = remaining
+	}
+	var buf bytes.Buffer
+	record := sessionRecord{ID: s.id, Values: s.values, Created: s.created, Accessed: s.accessed}
+	if err := gob.NewEncoder(&buf).Encode(&record); err != nil {
+		return err
+	}
+	value, err := s.store.Save(s.id, buf.Bytes(), ttl)
+	if err != nil {
+		return err
+	}
+	s.cookie = value
+	s.setCookie(value, int(ttl/time.Second))
+	return nil
+}
+
+//Regenerate 更换会话id并删除旧的会话,保留会话中的值,登录等权限变化后调用以防止会话固定攻击
+//	之后需要调用Save
+func (s *Session) Regenerate() error {
+	if s.cookie != "" {
+		if err := s.store.Delete(s.cookie); err != nil {
+			return err
+		}
+		s.cookie = ""
+	}
+	s.id = newSessionID()
+	s.created = time.Now()
+	return nil
+}
+
+//Destroy 删除会话与cookie,之后s为一个新的空会话
+func (s *Session) Destroy() error {
+	if s.cookie != "" {
+		if err := s.store.Delete(s.cookie); err != nil {
+			return err
+		}
+		s.cookie = ""
+	}
+	s.setCookie("", -1)
+	s.reset()
+	return nil
+}
+
+//setCookie 设定会话cookie,替换本次响应中已经设定的同名cookie
+func (s *Session) setCookie(value string, maxAge int) {
+	header := s.c.Writer.Header()
+	cookies := header.Values("Set-Cookie")
+	header.Del("Set-Cookie")
+	for _, cookie := range cookies {
+		if !strings.HasPrefix(cookie, s.options.CookieName+"=") {
+			header.Add("Set-Cookie", cookie)
+		}
+	}
+	http.SetCookie(s.c.Writer, &http.Cookie{
+		Name:     s.options.CookieName,
+		Value:    value,
+		Path:     s.options.Path,
+		Domain:   s.options.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.options.Secure,
+		HttpOnly: true,
+		SameSite: s.options.SameSite,
+	})
+}
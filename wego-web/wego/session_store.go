@@ -0,0 +1,242 @@
+package wego
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Store 保存会话数据
+//	服务端的Store以会话id作为cookie的值,CookieStore则将数据签名加密后直接保存在cookie中
+type Store interface {
+	//Load 返回cookie值对应的会话数据,不存在或已过期时返回nil
+	Load(value string) ([]byte, error)
+	//Save 保存会话数据,ttl后过期,返回需要写入cookie的值
+	Save(id string, data []byte, ttl time.Duration) (string, error)
+	//Delete 删除cookie值对应的会话
+	Delete(value string) error
+}
+
+//maxCookieSize 为浏览器可以保存的单个cookie的最大字节数
+const maxCookieSize = 4096
+
+//CookieStore 将会话数据使用HMAC-SHA256签名后保存在cookie中,提供加密密钥时使用AES-GCM加密
+type CookieStore struct {
+	codecs []cookieCodec
+}
+
+type cookieCodec struct {
+	hashKey []byte
+	block   cipher.AEAD
+}
+
+//NewCookieStore 使用成对的签名密钥与加密密钥创建CookieStore
+//	加密密钥可以为nil,此时只签名不加密,否则长度必须为16、24或32字节
+//	第一对密钥用于保存,所有的密钥都可以用于读取,因此轮换密钥时将新的密钥放在最前面
+//	NewCookieStore([]byte(newHashKey), []byte(newBlockKey), []byte(oldHashKey), []byte(oldBlockKey))
+func NewCookieStore(keyPairs ...[]byte) *CookieStore {
+	if len(keyPairs) == 0 {
+		panic("wego: NewCookieStore requires at least one hash key")
+	}
+	store := &CookieStore{}
+	for i := 0; i < len(keyPairs); i += 2 {
+		codec := cookieCodec{hashKey: keyPairs[i]}
+		if len(codec.hashKey) == 0 {
+			panic("wego: hash key of CookieStore must not be empty")
+		}
+		if i+1 < len(keyPairs) && keyPairs[i+1] != nil {
+			block, err := aes.NewCipher(keyPairs[i+1])
+			if err != nil {
+				panic(fmt.Sprintf("wego: invalid block key of CookieStore: %v", err))
+			}
+			codec.block, _ = cipher.NewGCM(block)
+		}
+		store.codecs = append(store.codecs, codec)
+	}
+	return store
+}
+
+//Load 使用所有的密钥尝试验证并解密
+func (s *CookieStore) Load(value string) ([]byte, error) {
+	for _, codec := range s.codecs {
+		if data, err := codec.decode(value); err == nil {
+			return data, nil
+		}
+	}
+	return nil, nil
+}
+
+//Save 使用第一对密钥签名并加密,结果超过4096字节时返回错误
+func (s *CookieStore) Save(id string, data []byte, ttl time.Duration) (string, error) {
+	value, err := s.codecs[0].encode(data, time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	if len(value) > maxCookieSize {
+		return "", fmt.Errorf("wego: session cookie is too large (%d bytes)", len(value))
+	}
+	return value, nil
+}
+
+//Delete 会话数据保存在cookie中,删除cookie即可
+func (s *CookieStore) Delete(value string) error {
+	return nil
+}
+
+//encode 返回 base64(过期时间|数据)|base64(签名),数据在启用加密时为密文
+func (c cookieCodec) encode(data []byte, expires time.Time) (string, error) {
+	if c.block != nil {
+		nonce := make([]byte, c.block.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		data = c.block.Seal(nonce, nonce, data, nil)
+	}
+	payload := fmt.Sprintf("%d|%s", expires.Unix(), base64.RawURLEncoding.EncodeToString(data))
+	payload = base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return payload + "|" + base64.RawURLEncoding.EncodeToString(c.sign(payload)), nil
+}
+
+func (c cookieCodec) decode(value string) ([]byte, error) {
+	i := strings.LastIndexByte(value, '|')
+	if i < 0 {
+		return nil, errors.New("wego: invalid session cookie")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil || !hmac.Equal(mac, c.sign(value[:i])) {
+		return nil, errors.New("wego: invalid session cookie signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value[:i])
+	if err != nil {
+		return nil, err
+	}
+	var expires int64
+	var encoded string
+	if j := strings.IndexByte(string(payload), '|'); j >= 0 {
+		fmt.Sscan(string(payload[:j]), &expires)
+		encoded = string(payload[j+1:])
+	}
+	if time.Now().Unix() > expires {
+		return nil, errors.New("wego: session cookie expired")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if c.block != nil {
+		nonceSize := c.block.NonceSize()
+		if len(data) < nonceSize {
+			return nil, errors.New("wego: invalid session cookie")
+		}
+		return c.block.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	}
+	return data, nil
+}
+
+func (c cookieCodec) sign(payload string) []byte {
+	h := hmac.New(sha256.New, c.hashKey)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+//MemoryStore 将会话保存在内存中,只适用于单个进程
+type MemoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+//NewMemoryStore 创建MemoryStore,过期的会话在读取或定期保存时被清除
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memorySession), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Load(value string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[value]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(session.expires) {
+		delete(s.sessions, value)
+		return nil, nil
+	}
+	return session.data, nil
+}
+
+func (s *MemoryStore) Save(id string, data []byte, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for key, session := range s.sessions {
+			if now.After(session.expires) {
+				delete(s.sessions, key)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.sessions[id] = memorySession{data: append([]byte(nil), data...), expires: now.Add(ttl)}
+	return id, nil
+}
+
+func (s *MemoryStore) Delete(value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, value)
+	return nil
+}
+
+//Cache 为 CacheStore 使用的外部缓存,如Redis或memcached的客户端
+//	wecache.Group 只支持通过Getter回源读取,可以将 Set 与 Delete 交给其回源的存储,
+//	并在 Get 中调用 Group.Get 后使用 ByteView.ByteSlice() 返回数据
+type Cache interface {
+	//Get 返回key对应的值,不存在或已过期时返回nil
+	Get(key string) ([]byte, error)
+	//Set 设定key对应的值,ttl后过期
+	Set(key string, value []byte, ttl time.Duration) error
+	//Delete 删除key对应的值
+	Delete(key string) error
+}
+
+//CacheStore 将会话保存在外部缓存中,可以在多个进程间共享
+//	会话按cookie的有效期在缓存中过期, Session.Destroy 与 Session.Regenerate 会删除缓存中的会话
+type CacheStore struct {
+	cache  Cache
+	prefix string
+}
+
+//NewCacheStore 创建CacheStore,prefix为缓存中会话键的前缀
+func NewCacheStore(cache Cache, prefix string) *CacheStore {
+	return &CacheStore{cache: cache, prefix: prefix}
+}
+
+func (s *CacheStore) Load(value string) ([]byte, error) {
+	return s.cache.Get(s.prefix + value)
+}
+
+//Save 以ttl保存会话,ttl不为正数时会话已经过期,直接删除,避免缓存将其视为永不过期
+func (s *CacheStore) Save(id string, data []byte, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		return id, s.cache.Delete(s.prefix + id)
+	}
+	return id, s.cache.Set(s.prefix+id, data, ttl)
+}
+
+func (s *CacheStore) Delete(value string) error {
+	return s.cache.Delete(s.prefix + value)
+}
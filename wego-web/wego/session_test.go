@@ -0,0 +1,212 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//mapCache 为测试使用的Cache,按ttl过期
+type mapCache struct {
+	mu      sync.Mutex
+	entries map[string]mapCacheEntry
+}
+
+type mapCacheEntry struct {
+	value   []byte
+	ttl     time.Duration
+	expires time.Time
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{entries: make(map[string]mapCacheEntry)}
+}
+
+func (m *mapCache) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, nil
+	}
+	return entry.value, nil
+}
+
+func (m *mapCache) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = mapCacheEntry{value: value, ttl: ttl, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *mapCache) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+func newSessionEngine(store Store, options SessionOptions) *Engine {
+	r := New()
+	r.Use(Sessions(store, options))
+	r.GET("/login", func(c *Context) {
+		s := c.Session()
+		s.Set("user", "geektutu")
+		s.Flash("welcome")
+		s.Regenerate()
+		if err := s.Save(); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/me", func(c *Context) {
+		s := c.Session()
+		flashes := s.Flashes()
+		if len(flashes) > 0 {
+			s.Save()
+		}
+		c.String(http.StatusOK, "%v %v", s.Get("user"), flashes)
+	})
+	r.GET("/logout", func(c *Context) {
+		c.Session().Destroy()
+	})
+	return r
+}
+
+//sessionRequest 发送携带cookie的请求,返回响应以及新的cookie
+func sessionRequest(r *Engine, path string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	r.ServeHTTP(w, req)
+	for _, c := range w.Result().Cookies() {
+		return w, c
+	}
+	return w, cookie
+}
+
+func TestSessionStores(t *testing.T) {
+	stores := map[string]Store{
+		"cookie": NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef")),
+		"memory": NewMemoryStore(),
+		"cache":  NewCacheStore(newMapCache(), "session:"),
+	}
+	for name, store := range stores {
+		r := newSessionEngine(store, SessionOptions{})
+		_, anonymous := sessionRequest(r, "/me", nil)
+		if anonymous != nil {
+			t.Fatalf("%s: unmodified new session shouldn't be saved", name)
+		}
+		w, cookie := sessionRequest(r, "/login", &http.Cookie{Name: "wego_session", Value: "fixed"})
+		if w.Code != http.StatusOK || cookie == nil || cookie.Value == "fixed" || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+			t.Fatalf("%s: unexpected login response %d %v", name, w.Code, cookie)
+		}
+		if strings.Count(w.Header().Get("Set-Cookie"), "wego_session") != 1 || len(w.Header().Values("Set-Cookie")) != 1 {
+			t.Fatalf("%s: session cookie should be set once, got %v", name, w.Header().Values("Set-Cookie"))
+		}
+		w, cookie = sessionRequest(r, "/me", cookie)
+		if w.Body.String() != "geektutu [welcome]" {
+			t.Fatalf("%s: unexpected session values %q", name, w.Body.String())
+		}
+		//Flash消息只读取一次
+		w, cookie = sessionRequest(r, "/me", cookie)
+		if w.Body.String() != "geektutu []" {
+			t.Fatalf("%s: flashes should be consumed, got %q", name, w.Body.String())
+		}
+		_, cleared := sessionRequest(r, "/logout", cookie)
+		if cleared.MaxAge >= 0 {
+			t.Fatalf("%s: logout should expire the cookie, got %v", name, cleared)
+		}
+		if name != "cookie" {
+			//服务端的会话被删除后旧的cookie失效
+			if w, _ := sessionRequest(r, "/me", cookie); w.Body.String() != "<nil> []" {
+				t.Fatalf("%s: destroyed session is still valid: %q", name, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestCookieStoreKeyRotation(t *testing.T) {
+	oldStore := NewCookieStore([]byte("old-hash"), nil)
+	_, cookie := sessionRequest(newSessionEngine(oldStore, SessionOptions{}), "/login", nil)
+
+	r := newSessionEngine(NewCookieStore([]byte("new-hash"), []byte("0123456789abcdef"), []byte("old-hash"), nil), SessionOptions{})
+	if w, _ := sessionRequest(r, "/me", cookie); w.Body.String() != "geektutu [welcome]" {
+		t.Fatalf("cookie signed with the old key should be accepted, got %q", w.Body.String())
+	}
+	tampered := *cookie
+	value := []byte(cookie.Value)
+	value[0] ^= 1
+	tampered.Value = string(value)
+	if w, _ := sessionRequest(r, "/me", &tampered); w.Body.String() != "<nil> []" {
+		t.Fatalf("tampered cookie should be rejected, got %q", w.Body.String())
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	r := newSessionEngine(store, SessionOptions{IdleTimeout: 50 * time.Millisecond, AbsoluteTimeout: 120 * time.Millisecond})
+	_, cookie := sessionRequest(r, "/login", nil)
+	//持续访问时空闲过期时间被延长,但不会超过绝对过期时间
+	for i := 0; i < 3; i++ {
+		time.Sleep(30 * time.Millisecond)
+		if w, _ := sessionRequest(r, "/me", cookie); !strings.HasPrefix(w.Body.String(), "geektutu") {
+			t.Fatalf("session expired too early: %q", w.Body.String())
+		}
+	}
+	time.Sleep(40 * time.Millisecond)
+	if w, _ := sessionRequest(r, "/me", cookie); w.Body.String() != "<nil> []" {
+		t.Fatalf("session should expire after the absolute timeout, got %q", w.Body.String())
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	cache := newMapCache()
+	r := newSessionEngine(NewCacheStore(cache, "session:"), SessionOptions{IdleTimeout: 50 * time.Millisecond})
+	_, cookie := sessionRequest(r, "/login", nil)
+	entry, ok := cache.entries["session:"+cookie.Value]
+	if !ok || entry.ttl != 50*time.Millisecond {
+		t.Fatalf("session should be saved with the idle timeout as ttl, got %v", entry.ttl)
+	}
+	//缓存中的会话过期后cookie失效
+	time.Sleep(60 * time.Millisecond)
+	if data, _ := cache.Get("session:" + cookie.Value); data != nil {
+		t.Fatal("session should expire in the cache")
+	}
+
+	_, cookie = sessionRequest(r, "/login", nil)
+	sessionRequest(r, "/logout", cookie)
+	if _, ok := cache.entries["session:"+cookie.Value]; ok {
+		t.Fatal("Destroy should delete the session from the cache")
+	}
+
+	store := NewCacheStore(cache, "session:")
+	store.Save("expired", []byte("data"), time.Minute)
+	store.Save("expired", []byte("data"), 0)
+	if _, ok := cache.entries["session:expired"]; ok {
+		t.Fatal("sessions saved without a positive ttl should be deleted instead of cached forever")
+	}
+}
+
+func TestSessionCopy(t *testing.T) {
+	r := New()
+	r.Use(Sessions(NewMemoryStore(), SessionOptions{}))
+	r.GET("/", func(c *Context) {
+		c.Session().Set("user", "tom")
+		cp := c.Copy()
+		cp.Session().Set("user", "jerry")
+		cp.Session().Set("role", "admin")
+		c.String(http.StatusOK, "%v %v", c.Session().Get("user"), c.Session().Get("role"))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Body.String() != "tom <nil>" {
+		t.Fatalf("values set on the session of a copy should not change the original, got %q", w.Body.String())
+	}
+}
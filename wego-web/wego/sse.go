@@ -0,0 +1,112 @@
+package wego
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+//MIMEEventStream 为Server-Sent Events的MIME类型
+const MIMEEventStream = "text/event-stream"
+
+//ServerSentEvent 为一条Server-Sent Events消息,实现了Render
+//	Data为字符串或[]byte时原样发送,其他类型编码为json,多行数据会被拆分为多个 data: 字段
+//	Retry 大于0时通知客户端断线后等待多少毫秒重连
+type ServerSentEvent struct {
+	Event string
+	ID    string
+	Retry uint
+	Data  interface{}
+}
+
+//eventFieldReplacer 去除event与id中的换行,防止伪造其他字段
+var eventFieldReplacer = strings.NewReplacer("\n", "", "\r", "")
+
+func (e ServerSentEvent) Render(w http.ResponseWriter) error {
+	var buf bytes.Buffer
+	if e.ID != "" {
+		buf.WriteString("id:")
+		buf.WriteString(eventFieldReplacer.Replace(e.ID))
+		buf.WriteByte('\n')
+	}
+	if e.Event != "" {
+		buf.WriteString("event:")
+		buf.WriteString(eventFieldReplacer.Replace(e.Event))
+		buf.WriteByte('\n')
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&buf, "retry:%d\n", e.Retry)
+	}
+	var data string
+	switch v := e.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data:")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return writeRendered(w, e, buf.Bytes())
+}
+
+func (e ServerSentEvent) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", MIMEEventStream)
+		header.Set("Cache-Control", "no-cache")
+		//禁止nginx等反向代理缓冲事件流
+		header.Set("X-Accel-Buffering", "no")
+	}
+}
+
+//SSEvent 发送一条名为name的事件并立即发送给客户端
+func (c *Context) SSEvent(name string, data interface{}) {
+	c.SendEvent(ServerSentEvent{Event: name, Data: data})
+}
+
+//SendEvent 发送一条事件,可以设定id与retry字段,发送后立即flush
+func (c *Context) SendEvent(event ServerSentEvent) {
+	c.Render(-1, event)
+	c.Writer.Flush()
+}
+
+//LastEventID 返回客户端重连时通过请求头 Last-Event-ID 携带的最后收到的事件id,
+//	不支持设置请求头的客户端可以使用查询参数 lastEventId
+func (c *Context) LastEventID() string {
+	if id := c.Req.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Query("lastEventId")
+}
+
+//Stream 循环调用step向客户端分块发送数据,每次调用后flush
+//	step返回false或客户端断开连接时结束,客户端断开连接时返回true
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.Writer)
+			c.Writer.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
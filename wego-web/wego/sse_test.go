@@ -0,0 +1,58 @@
+package wego
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestSSEvent(t *testing.T) {
+	r := New()
+	r.GET("/events", func(c *Context) {
+		//从客户端收到的最后一条事件之后继续发送
+		start, _ := strconv.Atoi(c.LastEventID())
+		c.SendEvent(ServerSentEvent{ID: strconv.Itoa(start + 1), Retry: 3000, Data: "line1\nline2"})
+		c.SSEvent("progress", H{"done": start + 1})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	r.ServeHTTP(w, req)
+	expected := "id:42\nretry:3000\ndata:line1\ndata:line2\n\nevent:progress\ndata:{\"done\":42}\n\n"
+	if w.Body.String() != expected || w.Header().Get("Content-Type") != MIMEEventStream || !w.Flushed {
+		t.Fatalf("unexpected event stream %q %q", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+}
+
+func TestStream(t *testing.T) {
+	r := New()
+	var clientGone bool
+	r.GET("/stream", func(c *Context) {
+		i := 0
+		clientGone = c.Stream(func(w io.Writer) bool {
+			i++
+			fmt.Fprintf(w, "%d;", i)
+			return i < 3
+		})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if w.Body.String() != "1;2;3;" || clientGone || !w.Flushed {
+		t.Fatalf("unexpected stream %q %v", w.Body.String(), clientGone)
+	}
+
+	//客户端断开连接后不再调用step
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(ctx))
+	if w.Body.Len() != 0 || !clientGone {
+		t.Fatalf("stream should stop when the client is gone, got %q", w.Body.String())
+	}
+}
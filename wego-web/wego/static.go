@@ -0,0 +1,297 @@
+package wego
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//StaticConfig 为 StaticWithConfig 的设定,零值字段使用默认值
+type StaticConfig struct {
+	//FS 为静态文件所在的文件系统,如 embed.FS 、 os.DirFS("public")
+	FS fs.FS
+	//Index 为请求目录时返回的文件,默认为 index.html
+	Index string
+	//Browse 为true时,目录中没有Index文件则列出目录中的文件,默认关闭
+	Browse bool
+	//MaxAge 大于0时设定 Cache-Control: public, max-age=... ,html文件总是使用 no-cache 以便及时更新
+	MaxAge time.Duration
+	//Immutable 为true时在 Cache-Control 中加入immutable,适用于文件名包含hash的资源
+	Immutable bool
+	//CacheControl 不为空时代替 MaxAge 与 Immutable ,根据文件的路径返回 Cache-Control ,返回空字符串时不设定
+	CacheControl func(name string) string
+	//SPA 为true时,不存在且没有扩展名的路径返回根目录的Index文件,用于单页应用的前端路由
+	//	有扩展名的路径(如 /app.js )仍返回404
+	SPA bool
+}
+
+//precompressedEncodings 为预压缩文件的编码与扩展名,按优先级排列
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+//Static 将服务器上root目录中的静态文件映射到url中
+//	Static("/assets", "./static")
+//	访问 /assets/js/app.js 即可返回 ./static/js/app.js
+func (group *RouterGroup) Static(relativePath string, root string) {
+	group.StaticFS(relativePath, dirFS(root))
+}
+
+//StaticFS 将fsys(如embed.FS)中的静态文件映射到url中
+//	使用embed.FS时通常需要先用 fs.Sub 去掉嵌入的目录名: sub, _ := fs.Sub(assets, "static")
+func (group *RouterGroup) StaticFS(relativePath string, fsys fs.FS) {
+	group.StaticWithConfig(relativePath, StaticConfig{FS: fsys})
+}
+
+//StaticWithConfig 按config将静态文件映射到url中
+//	响应带有根据文件内容生成的ETag,以及文件的修改时间(embed.FS中的文件没有修改时间),支持条件请求与Range请求
+//	请求头Accept-Encoding允许时,优先返回同一目录下预压缩的 .br 与 .gz 文件
+func (group *RouterGroup) StaticWithConfig(relativePath string, config StaticConfig) {
+	if config.FS == nil {
+		panic("wego: StaticConfig.FS must not be nil")
+	}
+	if config.Index == "" {
+		config.Index = "index.html"
+	}
+	handler := newStaticServer(config).handle
+	urlPattern := path.Join(relativePath, "/*filepath")
+	group.GET(urlPattern, handler)
+}
+
+//StaticFile 将服务器上的单个文件映射到url中
+//	StaticFile("/favicon.ico", "./static/favicon.ico")
+func (group *RouterGroup) StaticFile(relativePath string, filePath string) {
+	group.StaticFileFS(relativePath, filepath.Base(filePath), dirFS(filepath.Dir(filePath)))
+}
+
+//StaticFileFS 将fsys中名为name的文件映射到url中
+func (group *RouterGroup) StaticFileFS(relativePath string, name string, fsys fs.FS) {
+	if strings.Contains(relativePath, ":") || strings.Contains(relativePath, "*") {
+		panic("wego: URL parameters can not be used when serving a static file")
+	}
+	s := newStaticServer(StaticConfig{FS: fsys})
+	handler := func(c *Context) {
+		info, err := fs.Stat(fsys, name)
+		if err != nil || info.IsDir() {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		s.serveFile(c, name, info)
+	}
+	group.GET(relativePath, handler)
+}
+
+//dirFS 与 http.Dir 相同,root为空时使用当前目录
+func dirFS(root string) fs.FS {
+	if root == "" {
+		root = "."
+	}
+	return os.DirFS(root)
+}
+
+//staticServer 返回config.FS中的文件
+type staticServer struct {
+	config StaticConfig
+	etags  sync.Map //文件名 -> *staticETag
+}
+
+//staticETag 缓存文件的ETag,文件大小或修改时间变化后重新计算
+type staticETag struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+func newStaticServer(config StaticConfig) *staticServer {
+	return &staticServer{config: config}
+}
+
+func (s *staticServer) handle(c *Context) {
+	//去除 .. 等路径片段,防止访问根目录之外的文件
+	name := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+	if name == "" {
+		name = "."
+	}
+	if s.serve(c, name) {
+		return
+	}
+	if s.config.SPA && path.Ext(name) == "" {
+		if info, err := fs.Stat(s.config.FS, s.config.Index); err == nil && !info.IsDir() {
+			s.serveFile(c, s.config.Index, info)
+			return
+		}
+	}
+	c.Status(http.StatusNotFound)
+}
+
+//serve 返回名为name的文件或目录,不存在时返回false
+func (s *staticServer) serve(c *Context, name string) bool {
+	info, err := fs.Stat(s.config.FS, name)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		s.serveFile(c, name, info)
+		return true
+	}
+	//目录的url需要以 / 结尾,否则页面中的相对路径无法正确解析
+	if !strings.HasSuffix(c.Req.URL.Path, "/") {
+		//使用相对路径,避免 //example.com 这样的路径被重定向到其他网站
+		target := path.Base(c.Req.URL.Path) + "/"
+		if c.Req.URL.RawQuery != "" {
+			target += "?" + c.Req.URL.RawQuery
+		}
+		http.Redirect(c.Writer, c.Req, target, http.StatusMovedPermanently)
+		return true
+	}
+	index := path.Join(name, s.config.Index)
+	if info, err := fs.Stat(s.config.FS, index); err == nil && !info.IsDir() {
+		s.serveFile(c, index, info)
+		return true
+	}
+	if !s.config.Browse {
+		return false
+	}
+	s.listDir(c, name)
+	return true
+}
+
+//serveFile 返回文件内容,存在客户端接受的预压缩文件时返回预压缩文件
+func (s *staticServer) serveFile(c *Context, name string, info fs.FileInfo) {
+	header := c.Writer.Header()
+	contentType := mime.TypeByExtension(path.Ext(name))
+	served, servedInfo, encoding := name, info, ""
+	accept := c.Req.Header.Get("Accept-Encoding")
+	for _, pc := range precompressedEncodings {
+		variant, err := fs.Stat(s.config.FS, name+pc.ext)
+		if err != nil || variant.IsDir() {
+			continue
+		}
+		//存在预压缩文件时响应内容取决于Accept-Encoding
+		if !strings.Contains(header.Get("Vary"), "Accept-Encoding") {
+			header.Add("Vary", "Accept-Encoding")
+		}
+		if encoding == "" && contentType != "" && acceptsEncoding(accept, pc.encoding) {
+			served, servedInfo, encoding = name+pc.ext, variant, pc.encoding
+		}
+	}
+
+	f, err := s.config.FS.Open(served)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	etag, err := s.etag(served, servedInfo, content)
+	if err != nil {
+		c.Fail(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if cacheControl := s.cacheControl(name); cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+	header.Set("ETag", etag)
+	//ServeContent处理 If-None-Match 、 If-Modified-Since 与Range请求,修改时间为零值时不设定Last-Modified
+	http.ServeContent(c.Writer, c.Req, name, servedInfo.ModTime(), content)
+}
+
+//etag 返回文件内容的sha256的前16字节,计算后content回到开头
+func (s *staticServer) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if v, ok := s.etags.Load(name); ok {
+		cached := v.(*staticETag)
+		if cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+			return cached.etag, nil
+		}
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.etags.Store(name, &staticETag{size: info.Size(), modTime: info.ModTime(), etag: etag})
+	return etag, nil
+}
+
+//cacheControl 返回name的Cache-Control
+func (s *staticServer) cacheControl(name string) string {
+	if s.config.CacheControl != nil {
+		return s.config.CacheControl(name)
+	}
+	if ext := path.Ext(name); ext == ".html" || ext == ".htm" {
+		return "no-cache"
+	}
+	if s.config.MaxAge <= 0 {
+		return ""
+	}
+	cacheControl := "public, max-age=" + strconv.FormatInt(int64(s.config.MaxAge/time.Second), 10)
+	if s.config.Immutable {
+		cacheControl += ", immutable"
+	}
+	return cacheControl
+}
+
+//listDir 返回列出目录中文件的html页面
+func (s *staticServer) listDir(c *Context, name string) {
+	entries, err := fs.ReadDir(s.config.FS, name)
+	if err != nil {
+		c.Fail(http.StatusInternalServerError, "failed to read directory")
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		href := url.URL{Path: entryName}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", template.HTMLEscapeString(href.String()), template.HTMLEscapeString(entryName))
+	}
+	buf.WriteString("</pre>\n")
+	c.SetHeader("Content-Type", MIMEHTML+"; charset=utf-8")
+	c.SetHeader("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.Write(buf.Bytes())
+}
+
+//acceptsEncoding 判断Accept-Encoding是否接受coding,q值为0表示不接受
+func acceptsEncoding(accept string, coding string) bool {
+	return encodingQuality(accept, coding) > 0
+}
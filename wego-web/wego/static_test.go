@@ -0,0 +1,152 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func serveStatic(r *Engine, method, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStatic(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0755)
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	os.WriteFile(filepath.Join(dir, "css/app.css"), []byte("body{}"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs/index.html"), []byte("<h1>docs</h1>"), 0644)
+	os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *"), 0644)
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(filepath.Join(dir, "css/app.css"), modTime, modTime)
+
+	r := New()
+	r.Static("/assets", dir)
+	r.StaticFile("/robots.txt", filepath.Join(dir, "robots.txt"))
+
+	w := serveStatic(r, http.MethodGet, "/assets/css/app.css", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "body{}" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("unexpected response %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") != modTime.Format(http.TimeFormat) {
+		t.Fatalf("missing validators: ETag=%q Last-Modified=%q", etag, w.Header().Get("Last-Modified"))
+	}
+	if w := serveStatic(r, http.MethodGet, "/assets/css/app.css", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match should return 304, got %d", w.Code)
+	}
+	if w := serveStatic(r, http.MethodGet, "/assets/css/app.css", map[string]string{"Range": "bytes=0-3"}); w.Code != http.StatusPartialContent || w.Body.String() != "body" {
+		t.Fatalf("unexpected range response %d %q", w.Code, w.Body.String())
+	}
+	if w := serveStatic(r, http.MethodHead, "/assets/css/app.css", nil); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("unexpected HEAD response %d %q", w.Code, w.Body.String())
+	}
+
+	w = serveStatic(r, http.MethodGet, "/assets/docs/", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "<h1>docs</h1>" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("directory should serve its index, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveStatic(r, http.MethodGet, "/assets/docs", nil); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/assets/docs/" {
+		t.Fatalf("unexpected redirect %d %q", w.Code, w.Header().Get("Location"))
+	}
+	//目录列表默认关闭
+	if w := serveStatic(r, http.MethodGet, "/assets/css/", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("directory listing should be disabled, got %d", w.Code)
+	}
+	for _, path := range []string{"/assets/missing.js", "/assets/../static_test.go", "/assets/%2e%2e/static_test.go"} {
+		if w := serveStatic(r, http.MethodGet, path, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, w.Code)
+		}
+	}
+	if w := serveStatic(r, http.MethodGet, "/robots.txt", nil); w.Code != http.StatusOK || w.Body.String() != "User-agent: *" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":     {Data: []byte("<div id=app></div>")},
+		"js/app.js":      {Data: []byte("console.log('raw')")},
+		"js/app.js.br":   {Data: []byte("brotli")},
+		"js/app.js.gz":   {Data: []byte("gzip")},
+		"js/vendor.js":   {Data: []byte("vendor")},
+		"img/logo.svg":   {Data: []byte("<svg/>")},
+		"img/a<b>.svg":   {Data: []byte("<svg/>")},
+		"fonts/main.ttf": {Data: []byte("font")},
+	}
+	r := New()
+	r.GET("/api/users", func(c *Context) {
+		c.String(http.StatusOK, "users")
+	})
+	r.StaticWithConfig("/", StaticConfig{FS: fsys, SPA: true, Browse: true, MaxAge: 24 * time.Hour, Immutable: true})
+
+	for _, tc := range []struct {
+		accept   string
+		encoding string
+		body     string
+	}{
+		{"gzip, br", "br", "brotli"},
+		{"gzip", "gzip", "gzip"},
+		{"br;q=0, gzip", "gzip", "gzip"},
+		{"", "", "console.log('raw')"},
+	} {
+		w := serveStatic(r, http.MethodGet, "/js/app.js", map[string]string{"Accept-Encoding": tc.accept})
+		if w.Header().Get("Content-Encoding") != tc.encoding || w.Body.String() != tc.body {
+			t.Fatalf("Accept-Encoding %q: unexpected response %q %q", tc.accept, w.Header().Get("Content-Encoding"), w.Body.String())
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") && !strings.HasPrefix(w.Header().Get("Content-Type"), "application/javascript") {
+			t.Fatalf("unexpected Content-Type %q", w.Header().Get("Content-Type"))
+		}
+		if w.Header().Get("Vary") != "Accept-Encoding" || w.Header().Get("Last-Modified") != "" {
+			t.Fatalf("unexpected headers %v", w.Header())
+		}
+		if w.Header().Get("Cache-Control") != "public, max-age=86400, immutable" {
+			t.Fatalf("unexpected Cache-Control %q", w.Header().Get("Cache-Control"))
+		}
+	}
+	if w := serveStatic(r, http.MethodGet, "/js/vendor.js", map[string]string{"Accept-Encoding": "gzip"}); w.Header().Get("Vary") != "" || w.Body.String() != "vendor" {
+		t.Fatalf("files without precompressed variants shouldn't vary, got %v", w.Header())
+	}
+
+	//单页应用的前端路由返回index.html,带扩展名的路径与其他路由不受影响
+	if w := serveStatic(r, http.MethodGet, "/users/1", nil); w.Code != http.StatusOK || w.Body.String() != "<div id=app></div>" {
+		t.Fatalf("unexpected SPA fallback %d %q", w.Code, w.Body.String())
+	}
+	if w := serveStatic(r, http.MethodGet, "/missing.js", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("missing assets should return 404, got %d", w.Code)
+	}
+	if w := serveStatic(r, http.MethodGet, "/api/users", nil); w.Body.String() != "users" {
+		t.Fatalf("static routes shouldn't shadow other routes, got %q", w.Body.String())
+	}
+
+	w := serveStatic(r, http.MethodGet, "/img/", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<a href="logo.svg">logo.svg</a>`) || !strings.Contains(w.Body.String(), "a&lt;b&gt;.svg") {
+		t.Fatalf("unexpected directory listing %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticCacheControl(t *testing.T) {
+	fsys := fstest.MapFS{"app.css": {Data: []byte("body{}")}}
+	r := New()
+	r.StaticWithConfig("/assets", StaticConfig{FS: fsys, CacheControl: func(name string) string {
+		return "private, max-age=60"
+	}})
+	r.StaticFileFS("/favicon.css", "app.css", fsys)
+	if w := serveStatic(r, http.MethodGet, "/assets/app.css", nil); w.Header().Get("Cache-Control") != "private, max-age=60" {
+		t.Fatalf("unexpected Cache-Control %q", w.Header().Get("Cache-Control"))
+	}
+	if w := serveStatic(r, http.MethodGet, "/favicon.css", nil); w.Header().Get("Cache-Control") != "" || w.Body.String() != "body{}" {
+		t.Fatalf("unexpected response %q %q", w.Header().Get("Cache-Control"), w.Body.String())
+	}
+}
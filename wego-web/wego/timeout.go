@@ -0,0 +1,227 @@
+package wego
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//TimeoutConfig 为 TimeoutWithConfig 的设定
+type TimeoutConfig struct {
+	Timeout time.Duration
+	//StatusCode 为超时时返回的状态码,默认为503,也可以设为504
+	StatusCode int
+	//Response 不为空时用于返回超时的响应,默认返回包含message的json
+	Response HandlerFunc
+	//Overrides 按路由单独设定超时时间,键为路由模式,如 /upload ,或加上请求方法,如 POST /upload
+	//	值不大于0时该路由不限时,可以用于WebSocket与Server-Sent Events等长连接
+	Overrides map[string]time.Duration
+}
+
+//Timeout 限制处理请求的时间,等同于 TimeoutWithConfig(TimeoutConfig{Timeout: d})
+func Timeout(d time.Duration) HandlerFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+//TimeoutWithConfig 为之后的处理器设定截止时间,超时后立即返回超时的响应,即使处理器仍在执行
+//	之后的处理器在新的goroutine中使用Context的副本执行,响应先写入缓冲区,超时后缓冲区被丢弃,
+//	c.Req.Context() 在超时后被取消,调用数据库或RPC时传入该context即可及时结束
+//	处理器不能使用 Hijack ,Flush 不会立即发送数据;处理器使用单独的Session副本,超时后对其的修改被丢弃
+func TimeoutWithConfig(config TimeoutConfig) HandlerFunc {
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	if config.Response == nil {
+		config.Response = func(c *Context) {
+			c.AbortWithStatusJSON(config.StatusCode, H{"message": http.StatusText(config.StatusCode)})
+		}
+	}
+	return func(c *Context) {
+		d := config.Timeout
+		if override, ok := config.Overrides[c.Method+" "+c.FullPath()]; ok {
+			d = override
+		} else if override, ok := config.Overrides[c.FullPath()]; ok && c.FullPath() != "" {
+			d = override
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Req.Context(), d)
+		defer cancel()
+		c.Req = c.Req.WithContext(ctx)
+
+		tw := &timeoutWriter{header: c.Writer.Header().Clone(), status: http.StatusOK, size: noWritten}
+		tc := c.Copy()
+		tc.handlers, tc.index = c.handlers, c.index
+		tc.errs = append([]error(nil), c.errs...)
+		tc.Writer = tw
+		//副本使用单独的请求,超时后处理器解析的multipart表单不会与c共享
+		tc.Req = c.Req.WithContext(ctx)
+		if c.Req.Body == &c.body {
+			//c被对象池复用后处理器仍可能读取请求体
+			tc.body = c.body
+			tc.Req.Body = &tc.body
+		}
+
+		done := make(chan struct{})
+		panics := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panics <- p
+				}
+			}()
+			tc.Next()
+			close(done)
+		}()
+
+		select {
+		case p := <-panics:
+			removeMultipartForm(tc.Req.MultipartForm, c.Req.MultipartForm)
+			//在原goroutine中重新panic,由 Recovery 处理
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			c.restore(tc)
+			//缓冲的响应头从c的响应头复制而来,用其替换c的响应头,使处理器删除的响应头同样生效
+			header := c.Writer.Header()
+			for k := range header {
+				if _, ok := tw.header[k]; !ok {
+					delete(header, k)
+				}
+			}
+			for k, v := range tw.header {
+				header[k] = v
+			}
+			if tw.size != noWritten {
+				c.Writer.WriteHeader(tw.status)
+				c.Writer.WriteHeaderNow()
+				c.Writer.Write(tw.buf)
+			} else {
+				c.Writer.WriteHeader(tw.status)
+			}
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			form := c.Req.MultipartForm
+			go func() {
+				//超时后处理器发生的panic只记录日志
+				select {
+				case p := <-panics:
+					tc.engine.Log.Errorf("panic after timeout %s %q: %v", tc.Method, tc.Path, p)
+				case <-done:
+				}
+				//处理器结束后删除其解析multipart表单时创建的临时文件
+				removeMultipartForm(tc.Req.MultipartForm, form)
+			}()
+			if ctx.Err() != context.DeadlineExceeded {
+				//客户端断开连接时请求的context被取消,不需要返回超时的响应
+				c.Abort()
+				return
+			}
+			c.errs = append(c.errs, ErrTimeout)
+			c.Abort()
+			config.Response(c)
+		}
+	}
+}
+
+//ErrTimeout 为超时的请求记录在访问日志中的错误
+var ErrTimeout = errors.New("wego: request timeout")
+
+//restore 将在副本中执行的处理器对Context的修改复制回c
+func (c *Context) restore(tc *Context) {
+	c.index = tc.index
+	c.Req = tc.Req
+	c.body = tc.body
+	c.errs = tc.errs
+	c.session, c.principal = tc.session.bind(c), tc.principal
+	c.csrfToken, c.csrfField, c.cspNonce = tc.csrfToken, tc.csrfField, tc.cspNonce
+	c.mu.Lock()
+	c.keys = tc.keys
+	c.mu.Unlock()
+}
+
+//timeoutWriter 将响应缓存在内存中,超时后写入返回 http.ErrHandlerTimeout
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      []byte
+	status   int
+	size     int
+	timedOut bool
+}
+
+var _ ResponseWriter = &timeoutWriter{}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && w.size == noWritten {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size == noWritten {
+		w.size = 0
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.size == noWritten {
+		w.size = 0
+	}
+	w.buf = append(w.buf, data...)
+	w.size += len(data)
+	return len(data), nil
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+func (w *timeoutWriter) Written() bool {
+	return w.Size() != noWritten
+}
+
+//Flush 响应在处理器结束后才发送,因此不做任何事
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("wego: Hijack is not supported within Timeout")
+}
+
+func (w *timeoutWriter) Pusher() http.Pusher {
+	return nil
+}
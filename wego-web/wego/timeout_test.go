@@ -0,0 +1,194 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	r := New()
+	r.Use(Recovery())
+	r.Use(func(c *Context) {
+		c.SetHeader("X-Before", "1")
+		c.Next()
+	})
+	r.Use(TimeoutWithConfig(TimeoutConfig{
+		Timeout:    20 * time.Millisecond,
+		StatusCode: http.StatusGatewayTimeout,
+		Overrides:  map[string]time.Duration{"/slow/allowed": time.Second, "GET /stream": 0},
+	}))
+	cancelled := make(chan error, 1)
+	r.GET("/fast", func(c *Context) {
+		c.Set("user", "tom")
+		c.SetHeader("X-Handler", "1")
+		c.String(http.StatusCreated, "fast")
+	})
+	r.GET("/slow", func(c *Context) {
+		<-c.Req.Context().Done()
+		cancelled <- c.Err()
+		time.Sleep(10 * time.Millisecond)
+		//超时后的写入被丢弃
+		c.String(http.StatusOK, "too late")
+	})
+	r.GET("/slow/allowed", func(c *Context) {
+		time.Sleep(40 * time.Millisecond)
+		c.String(http.StatusOK, "allowed")
+	})
+	r.GET("/stream", func(c *Context) {
+		if _, ok := c.Deadline(); ok {
+			t.Error("routes overridden with 0 should not have a deadline")
+		}
+		c.String(http.StatusOK, "stream")
+	})
+	r.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+
+	for _, tc := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/fast", http.StatusCreated, "fast"},
+		{"/slow", http.StatusGatewayTimeout, `{"message":"Gateway Timeout"}`},
+		{"/slow/allowed", http.StatusOK, "allowed"},
+		{"/stream", http.StatusOK, "stream"},
+		{"/panic", http.StatusInternalServerError, `{"message":"Internal Server Error"}`},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.code || strings.TrimSpace(w.Body.String()) != tc.body || w.Header().Get("X-Before") != "1" {
+			t.Fatalf("%s: expected %d %q, got %d %q", tc.path, tc.code, tc.body, w.Code, w.Body.String())
+		}
+		if tc.path == "/fast" && w.Header().Get("X-Handler") != "1" {
+			t.Fatal("headers set by the handler should be sent")
+		}
+	}
+	if err := <-cancelled; err == nil {
+		t.Fatal("the request context should be cancelled on timeout")
+	}
+}
+
+func TestTimeoutConcurrent(t *testing.T) {
+	r := New()
+	var user string
+	var mu sync.Mutex
+	r.Use(func(c *Context) {
+		c.Next()
+		mu.Lock()
+		user = c.GetString("user")
+		mu.Unlock()
+	})
+	r.Use(Timeout(5 * time.Millisecond))
+	r.GET("/", func(c *Context) {
+		c.Set("user", "tom")
+		if c.Query("slow") != "" {
+			time.Sleep(10 * time.Millisecond)
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := "/"
+			if i%2 == 0 {
+				path = "/?slow=1"
+			}
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}(i)
+	}
+	wg.Wait()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || user != "tom" {
+		t.Fatalf("values set in the handler should be visible to outer middlewares, got %d %q", w.Code, user)
+	}
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestTimeoutSession(t *testing.T) {
+	r := New()
+	r.Use(Sessions(NewMemoryStore(), SessionOptions{}))
+	r.Use(func(c *Context) {
+		c.SetHeader("X-Outer", "1")
+		c.SetHeader("X-Removed", "1")
+		c.Next()
+	})
+	r.Use(Timeout(time.Second))
+	r.GET("/login", func(c *Context) {
+		c.Writer.Header().Del("X-Removed")
+		s := c.Session()
+		s.Set("user", "tom")
+		if err := s.Save(); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Set-Cookie"), "wego_session=") || w.Header().Get("X-Outer") != "1" {
+		t.Fatalf("session cookie saved within Timeout should be kept, got %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("X-Removed") != "" {
+		t.Fatalf("headers deleted by the handler should stay deleted, got %v", w.Header())
+	}
+}
+
+func TestTimeoutSessionAfterTimeout(t *testing.T) {
+	r := New()
+	r.Use(Sessions(NewMemoryStore(), SessionOptions{}))
+	r.Use(func(c *Context) {
+		c.Next()
+		//超时后外层修改会话时,处理器仍在修改自己的副本
+		for i := 0; i < 1000; i++ {
+			c.Session().Set("outer", i)
+		}
+	})
+	r.Use(Timeout(10 * time.Millisecond))
+	finished := make(chan struct{})
+	r.GET("/", func(c *Context) {
+		defer close(finished)
+		c.Session().Set("user", "tom")
+		<-c.Done()
+		for i := 0; i < 1000; i++ {
+			c.Session().Set("count", i)
+		}
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	<-finished
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a timeout response, got %d", w.Code)
+	}
+}
+
+func TestTimeoutClientCancel(t *testing.T) {
+	r := New()
+	responded := false
+	r.Use(TimeoutWithConfig(TimeoutConfig{Timeout: time.Second, Response: func(c *Context) {
+		responded = true
+	}}))
+	started := make(chan struct{})
+	r.GET("/", func(c *Context) {
+		close(started)
+		<-c.Done()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if responded || w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("client cancellation should not be reported as a timeout, got %d %q", w.Code, w.Body.String())
+	}
+}
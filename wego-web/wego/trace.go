@@ -0,0 +1,315 @@
+package wego
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//请求ID与W3C Trace Context使用的请求头
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+//TraceID 为16字节的链路ID
+type TraceID [16]byte
+
+//SpanID 为8字节的span ID
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+//IsValid 全为0的ID无效
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+func (s SpanID) IsValid() bool  { return s != SpanID{} }
+
+//MarshalText 使ID在json中编码为十六进制字符串
+func (t TraceID) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+func (s SpanID) MarshalText() ([]byte, error)  { return []byte(s.String()), nil }
+
+//SpanContext 为需要传递给下游服务的链路信息
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+//IsValid 判断TraceID与SpanID是否有效
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+//Traceparent 返回 traceparent 请求头的值,如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+//ParseTraceparent 解析 traceparent 请求头,格式无效时返回false
+//	高于00的版本只解析前四个字段,以兼容之后的版本
+func ParseTraceparent(s string) (SpanContext, bool) {
+	var sc SpanContext
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || (len(s) > 55 && (s[:2] == "00" || s[55] != '-')) {
+		return sc, false
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' || s[:2] == "ff" {
+		return sc, false
+	}
+	var version, flags [1]byte
+	if !decodeLowerHex(version[:], s[:2]) || !decodeLowerHex(sc.TraceID[:], s[3:35]) ||
+		!decodeLowerHex(sc.SpanID[:], s[36:52]) || !decodeLowerHex(flags[:], s[53:55]) {
+		return sc, false
+	}
+	if !sc.IsValid() {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+//decodeLowerHex 规范要求使用小写的十六进制
+func decodeLowerHex(dst []byte, s string) bool {
+	if strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+//Span 记录一次请求的处理过程
+type Span struct {
+	Name       string                 `json:"name"` //如 GET /users/:id
+	TraceID    TraceID                `json:"trace_id"`
+	SpanID     SpanID                 `json:"span_id"`
+	ParentID   SpanID                 `json:"parent_id"` //没有上游服务时全为0
+	TraceState string                 `json:"trace_state,omitempty"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+//SpanExporter 导出请求结束后的Span,需要可以并发调用
+type SpanExporter interface {
+	Export(span *Span) error
+}
+
+//MemoryExporter 在内存中保存导出的Span,用于测试
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+//NewMemoryExporter 创建MemoryExporter
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (e *MemoryExporter) Export(span *Span) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+	return nil
+}
+
+//Spans 返回已导出的所有Span
+func (e *MemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+//Reset 清空已导出的Span
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
+
+//FileExporter 将Span以JSON Lines格式追加到文件中
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+//NewFileExporter 打开或创建path,导出的Span追加到文件末尾
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (e *FileExporter) Export(span *Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(span)
+}
+
+//Close 关闭文件
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+//TraceConfig 为 Trace 中间件的设定
+type TraceConfig struct {
+	//Exporter 为空时不导出Span,请求ID与链路信息仍然会传递
+	Exporter SpanExporter
+	//GenerateRequestID 生成请求ID,默认为随机的UUID
+	GenerateRequestID func() string
+}
+
+type traceContextKey struct{}
+
+//traceInfo 保存在请求的context中
+type traceInfo struct {
+	requestID string
+	span      SpanContext
+}
+
+//Trace 读取或生成 X-Request-ID ,解析 traceparent 与 tracestate 并为每个请求创建一个Span
+//	请求ID与新Span的链路信息通过响应头返回,并保存在 c.Req 的context中,
+//	可以通过 RequestIDFromContext 、 SpanContextFromContext 获取,或用 InjectTrace 传递给下游服务
+//	上游未设定traceparent时开始新的链路并采样,否则沿用上游的链路ID与采样标志,只导出采样的Span
+func Trace(config TraceConfig) HandlerFunc {
+	if config.GenerateRequestID == nil {
+		config.GenerateRequestID = newRequestID
+	}
+	return func(c *Context) {
+		requestID := c.Req.Header.Get(HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = config.GenerateRequestID()
+		}
+		parent, ok := ParseTraceparent(c.Req.Header.Get(HeaderTraceparent))
+		if ok {
+			parent.TraceState = strings.TrimSpace(strings.Join(c.Req.Header.Values(HeaderTracestate), ","))
+		} else {
+			parent = SpanContext{Sampled: true}
+			rand.Read(parent.TraceID[:])
+		}
+		sc := SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled, TraceState: parent.TraceState}
+		rand.Read(sc.SpanID[:])
+
+		header := c.Writer.Header()
+		header.Set(HeaderRequestID, requestID)
+		header.Set(HeaderTraceparent, sc.Traceparent())
+		if sc.TraceState != "" {
+			header.Set(HeaderTracestate, sc.TraceState)
+		}
+		c.Req = c.Req.WithContext(context.WithValue(c.Req.Context(), traceContextKey{}, &traceInfo{requestID: requestID, span: sc}))
+
+		start := time.Now()
+		c.Next()
+		if config.Exporter == nil || !sc.Sampled {
+			return
+		}
+		name := c.FullPath()
+		if name == "" {
+			//未匹配到路由时不使用原始路径,避免Span名称过多
+			name = "unmatched"
+		}
+		span := &Span{
+			Name:       c.Method + " " + name,
+			TraceID:    sc.TraceID,
+			SpanID:     sc.SpanID,
+			ParentID:   parent.SpanID,
+			TraceState: sc.TraceState,
+			Start:      start,
+			End:        time.Now(),
+			Attributes: map[string]interface{}{
+				"http.method":      c.Method,
+				"http.route":       c.FullPath(),
+				"http.target":      c.Req.URL.RequestURI(),
+				"http.status_code": c.Writer.Status(),
+				"http.client_ip":   c.ClientIP(),
+				"request_id":       requestID,
+			},
+		}
+		if len(c.errs) > 0 {
+			span.Attributes["error"] = c.errs[len(c.errs)-1].Error()
+		}
+		if err := config.Exporter.Export(span); err != nil {
+			c.engine.Log.Errorf("export span %s: %v", span.SpanID, err)
+		}
+	}
+}
+
+//validRequestID 只接受不超过128个字符的可打印ASCII字符,防止日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+//newRequestID 生成随机的UUID(版本4)
+func newRequestID() string {
+	var u UUID
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u.String()
+}
+
+//RequestIDFromContext 返回 Trace 中间件保存在ctx中的请求ID
+func RequestIDFromContext(ctx context.Context) string {
+	if info, ok := ctx.Value(traceContextKey{}).(*traceInfo); ok {
+		return info.requestID
+	}
+	return ""
+}
+
+//SpanContextFromContext 返回 Trace 中间件为当前请求创建的Span的链路信息
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if info, ok := ctx.Value(traceContextKey{}).(*traceInfo); ok {
+		return info.span, true
+	}
+	return SpanContext{}, false
+}
+
+//InjectTrace 将ctx中的请求ID与链路信息设定到发往下游服务的请求头中
+func InjectTrace(ctx context.Context, header http.Header) {
+	info, ok := ctx.Value(traceContextKey{}).(*traceInfo)
+	if !ok {
+		return
+	}
+	header.Set(HeaderRequestID, info.requestID)
+	header.Set(HeaderTraceparent, info.span.Traceparent())
+	if info.span.TraceState != "" {
+		header.Set(HeaderTracestate, info.span.TraceState)
+	} else {
+		header.Del(HeaderTracestate)
+	}
+}
+
+//RequestID 返回 Trace 中间件设定的请求ID
+func (c *Context) RequestID() string {
+	return RequestIDFromContext(c.Req.Context())
+}
+
+//SpanContext 返回 Trace 中间件为当前请求创建的Span的链路信息
+func (c *Context) SpanContext() (SpanContext, bool) {
+	return SpanContextFromContext(c.Req.Context())
+}
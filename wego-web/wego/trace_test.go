@@ -0,0 +1,131 @@
+package wego
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	for _, tc := range []struct {
+		header  string
+		valid   bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+	} {
+		sc, ok := ParseTraceparent(tc.header)
+		if ok != tc.valid || sc.Sampled != tc.sampled {
+			t.Fatalf("%q: expected %v %v, got %v %v", tc.header, tc.valid, tc.sampled, ok, sc.Sampled)
+		}
+		if ok && tc.header[:2] == "00" && sc.Traceparent() != tc.header {
+			t.Fatalf("%q: round trip got %q", tc.header, sc.Traceparent())
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	exporter := NewMemoryExporter()
+	r := New()
+	r.Use(Trace(TraceConfig{Exporter: exporter}))
+	var outgoing http.Header
+	r.GET("/users/:id", func(c *Context) {
+		outgoing = http.Header{}
+		//Context实现了context.Context,可以直接传给InjectTrace
+		InjectTrace(c, outgoing)
+		c.String(http.StatusOK, c.RequestID())
+	})
+
+	//上游传入请求ID与链路信息
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	req.Header.Set(HeaderTraceparent, parent)
+	req.Header.Set(HeaderTracestate, "vendor=value")
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "req-1" || w.Header().Get(HeaderRequestID) != "req-1" {
+		t.Fatalf("incoming request ID should be kept, got %q", w.Body.String())
+	}
+	sc, ok := ParseTraceparent(w.Header().Get(HeaderTraceparent))
+	if !ok || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() == "00f067aa0ba902b7" {
+		t.Fatalf("a child span of the incoming trace should be created, got %q", w.Header().Get(HeaderTraceparent))
+	}
+	if outgoing.Get(HeaderTraceparent) != w.Header().Get(HeaderTraceparent) || outgoing.Get(HeaderTracestate) != "vendor=value" || outgoing.Get(HeaderRequestID) != "req-1" {
+		t.Fatalf("unexpected outgoing headers %v", outgoing)
+	}
+	spans := exporter.Spans()
+	if len(spans) != 1 || spans[0].Name != "GET /users/:id" || spans[0].ParentID.String() != "00f067aa0ba902b7" ||
+		spans[0].Attributes["http.status_code"] != http.StatusOK || spans[0].Attributes["request_id"] != "req-1" {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+
+	//未采样的链路不导出,无效的请求ID被替换
+	exporter.Reset()
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/users/2", nil)
+	req.Header.Set(HeaderRequestID, "bad id\n")
+	req.Header.Set(HeaderTraceparent, strings.TrimSuffix(parent, "01")+"00")
+	r.ServeHTTP(w, req)
+	if _, err := ParseUUID(w.Body.String()); err != nil {
+		t.Fatalf("a UUID request ID should be generated, got %q", w.Body.String())
+	}
+	if len(exporter.Spans()) != 0 {
+		t.Fatal("unsampled spans should not be exported")
+	}
+
+	//没有上游时开始新的链路
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	spans = exporter.Spans()
+	if len(spans) != 1 || spans[0].Name != "GET unmatched" || spans[0].ParentID.IsValid() || !spans[0].TraceID.IsValid() {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.Use(Trace(TraceConfig{Exporter: exporter}))
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	for i := 0; i < 3; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); lines++ {
+		var span map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &span); err != nil || len(span["trace_id"].(string)) != 32 || span["name"] != "GET /" {
+			t.Fatalf("invalid span line %q: %v", scanner.Text(), err)
+		}
+	}
+	if lines != 3 {
+		t.Fatalf("expected 3 spans, got %d", lines)
+	}
+}
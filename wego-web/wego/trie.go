@@ -6,16 +6,38 @@ import (
 )
 
 //pattern 路由匹配模式: 基本网址后的路径
+//	/hello/:name     : 开头的部分匹配一段路径,值存入同名参数
+//	/users/:id<int>  参数可以带有约束,见 constraint.go
+//	/assets/*filepath * 开头的部分匹配剩余的全部路径,只能出现在末尾
 
+//nodeKind 节点类型,查找时按 static > param(带约束的优先) > catchAll 的优先级匹配
+type nodeKind uint8
+
+const (
+	static   nodeKind = iota //静态路径
+	param                    //:name 参数
+	catchAll                 //*name 通配
+)
+
+//node 压缩前缀树(Radix Tree)的节点
+//	静态路径按公共前缀压缩在同一个节点中,参数与通配节点单独成为子节点
 type node struct {
-	pattern  string  //待匹配路由模式
-	part     string  //路由中的一部分
-	children []*node //子节点
-	isWild   bool    //是否为非精确匹配
+	pattern       string                       //待匹配路由模式,只有注册了路由的节点才不为空
+	part          string                       //static: 压缩后的路径片段; param: :name<约束>; catchAll: *name
+	kind          nodeKind                     //节点类型
+	key           string                       //参数名
+	match         func(string) bool            //参数约束,为nil时不限制参数值
+	indices       string                       //静态子节点part的首字节,与children一一对应
+	children      []*node                      //静态子节点
+	paramChildren []*node                      //参数子节点,带约束的节点排在前面
+	catchChild    *node                        //通配子节点
+	handlers      []HandlerFunc                //路由的完整处理链
+	name          string                       //路由名称,见 Route.Name
+	matchers      map[string]func(string) bool //路由中带约束的参数,用于生成url时检查参数值
 }
 
 func (n *node) String() string {
-	return fmt.Sprintf("node{pattern=%s, part=%s, isWild=%t}", n.pattern, n.part, n.isWild)
+	return fmt.Sprintf("node{pattern=%s, part=%s, isWild=%t}", n.pattern, n.part, n.kind != static)
 }
 
 func (n *node) travel(list *[]*node) {
@@ -25,91 +47,268 @@ func (n *node) travel(list *[]*node) {
 	for _, child := range n.children {
 		child.travel(list)
 	}
+	for _, child := range n.paramChildren {
+		child.travel(list)
+	}
+	if n.catchChild != nil {
+		n.catchChild.travel(list)
+	}
 }
 
-//matchChild 在n的孩子节点中查找第一个匹配的节点,用于插入
-func (n *node) matchChild(part string) *node {
-	//优先匹配可精确匹配的节点
-	for _, child := range n.children {
-		if child.part == part {
-			return child
+//longestCommonPrefix 返回a与b公共前缀的长度
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+//wildcardSpan 返回path中第一个参数或通配部分的起止位置,不存在时start为-1
+//	只有位于一段路径开头的 : 与 * 才被视为参数或通配,与parsePattern保持一致
+func wildcardSpan(path string) (start int, end int) {
+	for i := 0; i < len(path); i++ {
+		if (path[i] == ':' || path[i] == '*') && (i == 0 || path[i-1] == '/') {
+			end = strings.IndexByte(path[i:], '/')
+			if end < 0 {
+				return i, len(path)
+			}
+			return i, i + end
 		}
 	}
-	//未找到可精确匹配的节点才查找模糊匹配的节点
-	for _, child := range n.children {
-		if child.isWild {
+	return -1, -1
+}
+
+//insertStatic 沿静态子节点插入路径s,必要时分裂已有节点,返回s结束处的节点
+func (n *node) insertStatic(s string) *node {
+	for s != "" {
+		i := strings.IndexByte(n.indices, s[0])
+		if i < 0 {
+			//没有相同首字节的子节点,直接创建
+			child := &node{part: s, kind: static}
+			n.indices += s[:1]
+			n.children = append(n.children, child)
 			return child
 		}
+		child := n.children[i]
+		l := longestCommonPrefix(s, child.part)
+		if l < len(child.part) {
+			//只匹配了子节点的一部分,在公共前缀处分裂
+			mid := &node{
+				part:     child.part[:l],
+				kind:     static,
+				indices:  child.part[l : l+1],
+				children: []*node{child},
+			}
+			child.part = child.part[l:]
+			n.children[i] = mid
+			child = mid
+		}
+		n = child
+		s = s[l:]
 	}
-	return nil
+	return n
 }
 
-//matchChildren 在n的孩子节点中查找所有匹配成功的节点,用于查询
-func (n *node) matchChildren(part string) []*node {
-	//保证精确匹配的节点优先遍历
-	nodes := make([]*node, 0)
-	wildNodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part {
-			nodes = append(nodes, child)
-		} else if child.isWild {
-			wildNodes = append(wildNodes, child)
+//insertWild 插入参数或通配子节点
+//	同一位置的多个参数必须带有不同的约束,无约束的参数与通配只能各有一个
+func (n *node) insertWild(wild string) (*node, error) {
+	key, constraint, err := parseWildcard(wild)
+	if err != nil {
+		return nil, err
+	}
+	if wild[0] == '*' {
+		if n.catchChild == nil {
+			n.catchChild = &node{part: wild, kind: catchAll, key: key}
+		} else if n.catchChild.part != wild {
+			return nil, fmt.Errorf("wildcard '%s' conflicts with existing wildcard '%s'", wild, n.catchChild.part)
+		}
+		return n.catchChild, nil
+	}
+	for _, child := range n.paramChildren {
+		_, childConstraint, _ := parseWildcard(child.part)
+		if childConstraint != constraint {
+			continue
+		}
+		if child.part != wild {
+			return nil, fmt.Errorf("wildcard '%s' conflicts with existing wildcard '%s'", wild, child.part)
 		}
+		return child, nil
 	}
-	nodes = append(nodes, wildNodes...)
-	return nodes
+	child := &node{part: wild, kind: param, key: key}
+	if constraint == "" {
+		n.paramChildren = append(n.paramChildren, child)
+		return child, nil
+	}
+	if child.match, err = compileConstraint(constraint); err != nil {
+		return nil, err
+	}
+	//带约束的参数插入到无约束的参数之前
+	i := len(n.paramChildren)
+	if i > 0 && n.paramChildren[i-1].match == nil {
+		i--
+	}
+	n.paramChildren = append(n.paramChildren, nil)
+	copy(n.paramChildren[i+1:], n.paramChildren[i:])
+	n.paramChildren[i] = child
+	return child, nil
 }
 
-//insert 插入节点
-func (n *node) insert(pattern string, parts []string, height int) {
-	//patten: 待匹配路由路径  parts: 分割后的pattern的各部分  height: 节点深度  n: 当前匹配到的节点
-	if len(parts) == height {
-		//一个路径中的每一个part存储一层节点中
-		//若深度与part的个数相同时,则已经匹配到了底层
-		//在最下面的叶子节点存储整个匹配路径
-		if n.pattern != "" {
-			//若n存储的pattern不为空,则说明该节点已匹配路由规则,此时路由规则产生冲突
-			panic(fmt.Sprintf("Route Conflict: %s : %s", n.pattern, pattern))
-		}
-		n.pattern = pattern
-		return
-	}
-	//part: 当前层需要匹配的部分
-	part := parts[height]
-	//查找是否有匹配的节点
-	child := n.matchChild(part)
-	if child == nil {
-		//若没有,则创建一个节点,加入当前节点的子节点
-		child = &node{
-			part:   part,
-			isWild: part[0] == ':' || part[0] == '*',
-		}
-		n.children = append(n.children, child)
-	}
-	//递归继续向下插入
-	child.insert(pattern, parts, height+1)
+//insert 插入路由模式,返回路由所在的节点,或路由冲突、模式不合法的错误
+func (n *node) insert(pattern string, handlers []HandlerFunc) (*node, error) {
+	path := pattern
+	var matchers map[string]func(string) bool
+	for {
+		i, end := wildcardSpan(path)
+		if i < 0 {
+			n = n.insertStatic(path)
+			break
+		}
+		n = n.insertStatic(path[:i])
+		wild := path[i:end]
+		if wild[0] == '*' && end != len(path) {
+			return nil, fmt.Errorf("catch-all routes are only allowed at the end of the route '%s'", pattern)
+		}
+		child, err := n.insertWild(wild)
+		if err != nil {
+			return nil, fmt.Errorf("%v in route '%s'", err, pattern)
+		}
+		if child.match != nil {
+			if matchers == nil {
+				matchers = make(map[string]func(string) bool)
+			}
+			matchers[child.key] = child.match
+		}
+		n = child
+		path = path[end:]
+	}
+	if n.pattern != "" {
+		//若n存储的pattern不为空,则说明该节点已匹配路由规则,此时路由规则产生冲突
+		return nil, fmt.Errorf("route '%s' conflicts with existing route '%s'", pattern, n.pattern)
+	}
+	n.pattern = pattern
+	n.handlers = handlers
+	n.matchers = matchers
+	return n, nil
 }
 
-//search 查找节点
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		//匹配到了底层或是当前节点保存的part的前缀为*时
-		//如果n的pattern保存了路由规则,则返回n,否则返回nil
-		if n.pattern == "" {
-			return nil
+//search 查找与path匹配的节点,匹配到的路径参数追加到params中
+//	按 static > param > catchAll 的优先级回溯查找,params容量足够时不会产生内存分配
+func (n *node) search(path string, params *Params) *node {
+	if path == "" {
+		if n.pattern != "" {
+			return n
+		}
+		//通配部分可以匹配空路径
+		if n.catchChild != nil {
+			n.catchChild.appendParam(params, "")
+			return n.catchChild
+		}
+		return nil
+	}
+	//优先匹配静态子节点
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.part) {
+			if result := child.search(path[len(child.part):], params); result != nil {
+				return result
+			}
 		}
-		return n
 	}
-	//part: 当前层需要查找的部分
-	part := parts[height]
-	//查找所有可以匹配的节点,包括动态匹配的节点
-	children := n.matchChildren(part)
-	for _, child := range children {
-		//对每个可以匹配的节点递归向下进行查找
-		result := child.search(parts, height+1)
-		if result != nil {
-			return result
+	//参数匹配到下一个 / 为止,且不能为空,不满足约束时继续尝试下一个参数节点
+	if len(n.paramChildren) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			value := path[:end]
+			size := len(*params)
+			for _, child := range n.paramChildren {
+				if child.match != nil && !child.match(value) {
+					continue
+				}
+				child.appendParam(params, value)
+				if result := child.search(path[end:], params); result != nil {
+					return result
+				}
+				*params = (*params)[:size]
+			}
 		}
 	}
+	//通配匹配剩余的全部路径
+	if child := n.catchChild; child != nil {
+		child.appendParam(params, path)
+		return child
+	}
 	return nil
 }
+
+//appendParam 将参数节点匹配到的值追加到params中,匿名通配不记录参数
+func (n *node) appendParam(params *Params, value string) {
+	if n.key != "" {
+		*params = append(*params, Param{Key: n.key, Value: value})
+	}
+}
+
+//findCaseInsensitivePath 忽略大小写查找path,返回按路由模式修正大小写后的路径
+//	fixTrailingSlash 为true时同时修正多余或缺少的末尾 /
+func (n *node) findCaseInsensitivePath(path string, fixTrailingSlash bool) (string, bool) {
+	buf, ok := n.findCaseInsensitive(path, make([]byte, 0, len(path)+1), fixTrailingSlash)
+	return string(buf), ok
+}
+
+func (n *node) findCaseInsensitive(path string, buf []byte, fixTrailingSlash bool) ([]byte, bool) {
+	if path == "" {
+		if n.pattern != "" || n.catchChild != nil {
+			return buf, true
+		}
+		if fixTrailingSlash {
+			//尝试补全末尾的 /
+			if i := strings.IndexByte(n.indices, '/'); i >= 0 {
+				child := n.children[i]
+				if child.part == "/" && (child.pattern != "" || child.catchChild != nil) {
+					return append(buf, child.part...), true
+				}
+			}
+		}
+		return nil, false
+	}
+	for _, child := range n.children {
+		l := len(child.part)
+		if len(path) >= l && strings.EqualFold(path[:l], child.part) {
+			if out, ok := child.findCaseInsensitive(path[l:], append(buf, child.part...), fixTrailingSlash); ok {
+				return out, true
+			}
+		} else if fixTrailingSlash && len(path)+1 == l && child.part[l-1] == '/' &&
+			strings.EqualFold(path, child.part[:l-1]) && (child.pattern != "" || child.catchChild != nil) {
+			//path只缺少末尾的 /
+			return append(buf, child.part...), true
+		}
+	}
+	if len(n.paramChildren) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		for _, child := range n.paramChildren {
+			if end == 0 {
+				break
+			}
+			if child.match != nil && !child.match(path[:end]) {
+				continue
+			}
+			if out, ok := child.findCaseInsensitive(path[end:], append(buf, path[:end]...), fixTrailingSlash); ok {
+				return out, true
+			}
+		}
+	}
+	if n.catchChild != nil {
+		return append(buf, path...), true
+	}
+	//path只多出末尾的 /
+	if fixTrailingSlash && path == "/" && n.pattern != "" {
+		return buf, true
+	}
+	return nil, false
+}
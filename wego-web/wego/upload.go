@@ -0,0 +1,134 @@
+package wego
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+)
+
+//ErrBodyTooLarge 请求体超出 Engine.MaxRequestBodySize 时返回,Bind系列方法遇到该错误时返回413
+var ErrBodyTooLarge = errors.New("wego: request body too large")
+
+//limitedBody 限制请求体的大小,超出时Read返回 ErrBodyTooLarge
+type limitedBody struct {
+	rc        io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	//多读一个字节以判断是否超出限制
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.rc.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n = int(b.remaining)
+	b.remaining = 0
+	b.exceeded = true
+	return n, ErrBodyTooLarge
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
+
+//bodyError 请求体超出限制时将解析请求体得到的错误替换为 ErrBodyTooLarge
+//	标准库解析表单时不会保留原始错误,因此不能使用errors.Is判断
+func (c *Context) bodyError(err error) error {
+	if err != nil && c.body.exceeded {
+		return ErrBodyTooLarge
+	}
+	return err
+}
+
+//parseForm 解析url中的参数与表单,multipart表单使用 Engine.MaxMultipartMemory 作为内存上限
+func (c *Context) parseForm() error {
+	if mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		return c.parseMultipart()
+	}
+	return c.bodyError(c.Req.ParseForm())
+}
+
+func (c *Context) parseMultipart() error {
+	return c.bodyError(c.Req.ParseMultipartForm(c.engine.MaxMultipartMemory))
+}
+
+//removeMultipartForm 删除form的临时文件,form与shared相同时由shared的所有者删除
+func removeMultipartForm(form, shared *multipart.Form) {
+	if form != nil && form != shared {
+		form.RemoveAll()
+	}
+}
+
+//MultipartForm 解析并返回multipart表单,包括上传的文件
+//	超出 Engine.MaxMultipartMemory 的文件被保存在临时文件中,请求结束后自动删除
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	err := c.parseMultipart()
+	return c.Req.MultipartForm, err
+}
+
+//FormFile 返回multipart表单中名为name的第一个文件
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if err := c.parseMultipart(); err != nil {
+		return nil, err
+	}
+	f, fh, err := c.Req.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return fh, nil
+}
+
+//SaveUploadedFile 将上传的文件保存到dst,目录不存在时自动创建
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, src)
+	return err
+}
+
+//StreamMultipart 按顺序将multipart请求体中的每一部分交给handle处理,不在内存或临时文件中缓存
+//	适合将很大的文件直接写入其他Writer,handle返回错误时停止读取
+//	使用后不能再调用 FormFile、MultipartForm、PostForm 等需要解析整个表单的方法
+func (c *Context) StreamMultipart(handle func(part *multipart.Part) error) error {
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return c.bodyError(err)
+		}
+		err = handle(part)
+		part.Close()
+		if err != nil {
+			return c.bodyError(err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package wego
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func multipartBody(t *testing.T, fields map[string]string, files map[string]string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	//按名称排序,使各个part的顺序固定
+	sort.Strings(names)
+	for _, name := range names {
+		fw, err := mw.CreateFormFile(name, name+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(files[name]))
+	}
+	mw.Close()
+	return body, mw.FormDataContentType()
+}
+
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	r := New()
+	r.MaxRequestBodySize = 1024
+	r.POST("/upload", func(c *Context) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.SaveUploadedFile(fh, filepath.Join(dir, "sub", fh.Filename)); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%s %s %d", c.PostForm("user"), fh.Filename, fh.Size)
+	})
+	r.POST("/stream", func(c *Context) {
+		var sb strings.Builder
+		err := c.StreamMultipart(func(part *multipart.Part) error {
+			sb.WriteString(part.FormName() + "=")
+			_, err := io.Copy(&sb, part)
+			sb.WriteString(";")
+			return err
+		})
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, sb.String())
+	})
+	r.POST("/bind", func(c *Context) {
+		var obj struct {
+			Name string `json:"name"`
+		}
+		if c.BindJSON(&obj) == nil {
+			c.String(http.StatusOK, obj.Name)
+		}
+	})
+
+	body, contentType := multipartBody(t, map[string]string{"user": "geektutu"}, map[string]string{"file": "hello"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	r.ServeHTTP(w, req)
+	saved, _ := os.ReadFile(filepath.Join(dir, "sub", "file.txt"))
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu file.txt 5" || string(saved) != "hello" {
+		t.Fatalf("unexpected upload response %d %q %q", w.Code, w.Body.String(), saved)
+	}
+
+	body, contentType = multipartBody(t, nil, map[string]string{"a": "1", "b": "2"})
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/stream", body)
+	req.Header.Set("Content-Type", contentType)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "a=1;b=2;" {
+		t.Fatalf("unexpected stream response %d %q", w.Code, w.Body.String())
+	}
+
+	//Content-Length超出限制时直接返回413
+	body, contentType = multipartBody(t, nil, map[string]string{"file": strings.Repeat("a", 2048)})
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+
+	//未知长度的请求体在读取时超出限制
+	for _, path := range []string{"/upload", "/stream", "/bind"} {
+		body, contentType = multipartBody(t, nil, map[string]string{"file": strings.Repeat("a", 2048)})
+		if path == "/bind" {
+			body = bytes.NewBufferString(`{"name":"` + strings.Repeat("a", 2048) + `"}`)
+			contentType = MIMEJSON
+		}
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, path, io.MultiReader(body))
+		req.ContentLength = -1
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(w, req)
+		if path == "/bind" && w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("%s: expected 413, got %d", path, w.Code)
+		}
+		if path != "/bind" && !strings.Contains(w.Body.String(), ErrBodyTooLarge.Error()) {
+			t.Fatalf("%s: expected ErrBodyTooLarge, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestUploadTempFilesRemoved(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	handler := func(c *Context) {
+		if _, err := c.FormFile("file"); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if c.FullPath() == "/slow" {
+			<-c.Done()
+		}
+		c.String(http.StatusOK, "ok")
+	}
+	//Trace与Timeout都会替换c.Req
+	for name, middleware := range map[string]HandlerFunc{
+		"trace":   Trace(TraceConfig{}),
+		"timeout": TimeoutWithConfig(TimeoutConfig{Timeout: time.Second, Overrides: map[string]time.Duration{"/slow": 20 * time.Millisecond}}),
+	} {
+		r := New()
+		//所有文件都保存在临时文件中
+		r.MaxMultipartMemory = 1
+		r.Use(middleware)
+		r.POST("/upload", handler)
+		r.POST("/slow", handler)
+		for _, path := range []string{"/upload", "/slow"} {
+			if name == "trace" && path == "/slow" {
+				continue
+			}
+			body, contentType := multipartBody(t, nil, map[string]string{"file": strings.Repeat("wego", 1024)})
+			req := httptest.NewRequest(http.MethodPost, path, body)
+			req.Header.Set("Content-Type", contentType)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			//超时的处理器结束后才删除
+			var entries []os.DirEntry
+			for i := 0; i < 50; i++ {
+				if entries, _ = os.ReadDir(tmp); len(entries) == 0 {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if len(entries) != 0 {
+				t.Fatalf("%s %s: temporary files of the multipart form should be removed, got %d", name, path, len(entries))
+			}
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package wego
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//校验规则写在结构体字段的 binding 标签中,多个规则使用 , 分隔:
+//	required    字段不能为零值,字符串、切片、map不能为空
+//	omitempty   字段为零值时跳过其他规则
+//	min=n max=n len=n  数字比较值,字符串比较字符数,切片与map比较长度
+//	email       字符串为合法的邮箱地址
+//	oneof=a b c 值为给出的选项之一
+//	dive        之后的规则作用于切片、数组或map中的每个元素
+//嵌套的结构体以及切片中的结构体会被递归校验
+
+//FieldError 描述一个字段未通过校验的原因
+type FieldError struct {
+	Field string      `json:"field"`           //字段路径,使用请求中的名称,如 address.city、items[0].name
+	Tag   string      `json:"tag"`             //未通过的规则
+	Param string      `json:"param,omitempty"` //规则的参数
+	Value interface{} `json:"value,omitempty"` //字段的值
+}
+
+func (e FieldError) Error() string {
+	rule := e.Tag
+	if e.Param != "" {
+		rule += "=" + e.Param
+	}
+	return fmt.Sprintf("field '%s' failed on the '%s' rule", e.Field, rule)
+}
+
+//ValidationErrors 为所有未通过校验的字段,可以直接作为JSON返回
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, e := range ve {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+//Validate 按 binding 标签校验obj,未通过时返回 ValidationErrors ,obj为nil时返回nil
+//	字段路径使用 json 标签中的名称,没有标签时使用字段名;请求绑定时使用对应绑定的标签
+func Validate(obj interface{}) error {
+	return validate(obj, "json")
+}
+
+//flatTags 为嵌套结构体与外层字段共用一个命名空间的标签,字段路径不包含外层字段
+var flatTags = map[string]bool{"form": true, "header": true, "uri": true}
+
+//validate 校验obj,字段路径使用tag标签中的名称,与请求中的键一致
+func validate(obj interface{}, tag string) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	var errs ValidationErrors
+	validateNested(v, tag, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+//validateNested 递归校验结构体以及容器中的结构体
+func validateNested(v reflect.Value, tag string, path string, errs *ValidationErrors) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			validateNested(v.Elem(), tag, path, errs)
+		}
+	case reflect.Struct:
+		if v.Type() != timeType {
+			validateStruct(v, tag, path, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateNested(v.Index(i), tag, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			validateNested(iter.Value(), tag, fmt.Sprintf("%s[%v]", path, iter.Key()), errs)
+		}
+	}
+}
+
+func validateStruct(v reflect.Value, tag string, path string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, _ := splitTag(field.Tag.Get(tag))
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		fieldPath := name
+		if path != "" && !flatTags[tag] {
+			fieldPath = path + "." + name
+		}
+		fv := v.Field(i)
+		rules := field.Tag.Get("binding")
+		if rules == "-" {
+			continue
+		}
+		if rules != "" && !validateField(fv, fieldPath, strings.Split(rules, ","), errs) {
+			//字段本身未通过校验时不再校验嵌套的字段
+			continue
+		}
+		nestedPath := fieldPath
+		if field.Anonymous && field.Tag.Get(tag) == "" {
+			//嵌入的结构体的字段与外层字段位于同一层
+			nestedPath = path
+		}
+		validateNested(fv, tag, nestedPath, errs)
+	}
+}
+
+//validateField 按rules校验字段,遇到第一个未通过的规则时记录错误并返回false
+func validateField(v reflect.Value, path string, rules []string, errs *ValidationErrors) bool {
+	for _, rule := range rules {
+		if rule == "omitempty" && isEmptyValue(v) {
+			return true
+		}
+	}
+	for i, rule := range rules {
+		name, param := rule, ""
+		if j := strings.IndexByte(rule, '='); j >= 0 {
+			name, param = rule[:j], rule[j+1:]
+		}
+		switch name {
+		case "omitempty":
+			continue
+		case "dive":
+			return validateElems(v, path, rules[i+1:], errs)
+		case "required":
+			if isEmptyValue(v) {
+				errs.add(path, name, param, v)
+				return false
+			}
+			continue
+		}
+		elem := v
+		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+			if elem.IsNil() {
+				//未设置的指针字段只校验 required
+				return true
+			}
+			elem = elem.Elem()
+		}
+		if !checkRule(elem, name, param) {
+			errs.add(path, name, param, elem)
+			return false
+		}
+	}
+	return true
+}
+
+//validateElems 使用rules校验容器中的每个元素
+func validateElems(v reflect.Value, path string, rules []string, errs *ValidationErrors) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	ok := true
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			ok = validateField(v.Index(i), fmt.Sprintf("%s[%d]", path, i), rules, errs) && ok
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			ok = validateField(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), rules, errs) && ok
+		}
+	default:
+		panic(fmt.Sprintf("wego: 'dive' can't be used on %s of type %s", path, v.Type()))
+	}
+	return ok
+}
+
+func (errs *ValidationErrors) add(path string, tag string, param string, v reflect.Value) {
+	e := FieldError{Field: path, Tag: tag, Param: param}
+	if v.IsValid() && v.CanInterface() {
+		e.Value = v.Interface()
+	}
+	*errs = append(*errs, e)
+}
+
+//isEmptyValue 判断值是否为零值,字符串、切片与map长度为0时视为空
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Invalid:
+		return true
+	}
+	return v.IsZero()
+}
+
+//checkRule 检查v是否满足除 required 外的规则,未知规则会导致panic
+func checkRule(v reflect.Value, name string, param string) bool {
+	switch name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("wego: invalid param for rule '%s': %q", name, param))
+		}
+		size, ok := sizeOf(v)
+		if !ok {
+			panic(fmt.Sprintf("wego: rule '%s' can't be used on type %s", name, v.Type()))
+		}
+		switch name {
+		case "min":
+			return size >= limit
+		case "max":
+			return size <= limit
+		default:
+			return size == limit
+		}
+	case "email":
+		if v.Kind() != reflect.String {
+			panic(fmt.Sprintf("wego: rule 'email' can't be used on type %s", v.Type()))
+		}
+		addr, err := mail.ParseAddress(v.String())
+		return err == nil && addr.Address == v.String()
+	case "oneof":
+		value := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if value == option {
+				return true
+			}
+		}
+		return false
+	}
+	panic(fmt.Sprintf("wego: unknown validation rule '%s'", name))
+}
+
+//sizeOf 返回用于 min、max、len 比较的数值
+func sizeOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
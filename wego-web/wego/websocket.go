@@ -0,0 +1,600 @@
+package wego
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+//websocket消息类型,与RFC 6455中的opcode相同
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+//websocket关闭码,见RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+//defaultWSReadLimit Upgrader.ReadLimit 为0时单条消息的最大字节数
+const defaultWSReadLimit = 1 << 20
+
+//maxControlPayload 控制帧的最大载荷
+const maxControlPayload = 125
+
+//websocketGUID 用于计算 Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+//ErrCloseSent 已发送关闭帧后继续写入时返回
+var ErrCloseSent = errors.New("wego: websocket: close frame already sent")
+
+//CloseError 为对方发送的关闭帧,ReadMessage 收到关闭帧后返回
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("wego: websocket: closed with code %d %s", e.Code, e.Text)
+}
+
+//wsViolation 为对方违反协议的错误,返回前已使用code关闭连接
+type wsViolation struct {
+	code int
+	text string
+}
+
+func (e *wsViolation) Error() string {
+	return "wego: websocket: " + e.text
+}
+
+//Upgrader 设定握手与连接的参数,零值可以直接使用
+type Upgrader struct {
+	//CheckOrigin 检查请求头Origin,返回false时以403拒绝握手
+	//	为nil时只允许未设置Origin或Origin与Host相同的请求
+	CheckOrigin func(req *http.Request) bool
+	//Subprotocols 为服务端支持的子协议,按客户端给出的顺序选择第一个支持的
+	Subprotocols []string
+	//ReadLimit 为单条消息(包括所有分片)的最大字节数,不大于0时使用1MB,超出时以1009关闭连接
+	ReadLimit int64
+	//HandshakeTimeout 为发送握手响应的超时时间,为0时不限制
+	HandshakeTimeout time.Duration
+}
+
+//WSHandler 处理升级后的websocket连接
+type WSHandler func(c *Context, conn *WSConn)
+
+//WS 注册websocket路由,组与middlewares中的中间件(鉴权、日志等)在升级之前执行,
+//	握手失败时返回错误状态码而不调用handler,handler返回后连接被关闭
+func (group *RouterGroup) WS(pattern string, handler WSHandler, middlewares ...HandlerFunc) *Route {
+	handlers := make([]HandlerFunc, 0, len(middlewares)+1)
+	handlers = append(handlers, middlewares...)
+	handlers = append(handlers, func(c *Context) {
+		conn, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(c, conn)
+	})
+	return group.GET(pattern, handlers...)
+}
+
+//Upgrade 使用 Engine.WebSocket 的设定将请求升级为websocket连接
+//	失败时已返回错误状态码并中断处理链
+func (c *Context) Upgrade() (*WSConn, error) {
+	return c.engine.WebSocket.Upgrade(c)
+}
+
+//Upgrade 完成RFC 6455握手并接管底层连接
+func (u *Upgrader) Upgrade(c *Context) (*WSConn, error) {
+	req := c.Req
+	if req.Method != http.MethodGet {
+		return nil, c.upgradeFail(http.StatusMethodNotAllowed, "websocket handshake requires GET")
+	}
+	if !headerContainsToken(req.Header, "Connection", "upgrade") || !headerContainsToken(req.Header, "Upgrade", "websocket") {
+		return nil, c.upgradeFail(http.StatusBadRequest, "not a websocket handshake")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return nil, c.upgradeFail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, c.upgradeFail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(req) {
+		return nil, c.upgradeFail(http.StatusForbidden, "websocket origin not allowed")
+	}
+	if c.Writer.Written() {
+		return nil, errors.New("wego: websocket: response already written")
+	}
+	//接管连接后状态码仅用于日志等中间件
+	c.Writer.WriteHeader(http.StatusSwitchingProtocols)
+
+	netConn, brw, err := c.Writer.Hijack()
+	if err != nil {
+		return nil, c.upgradeFail(http.StatusInternalServerError, err.Error())
+	}
+
+	header := c.Writer.Header().Clone()
+	header.Set("Upgrade", "websocket")
+	header.Set("Connection", "Upgrade")
+	header.Set("Sec-WebSocket-Accept", acceptKey(key))
+	subprotocol := u.selectSubprotocol(req)
+	if subprotocol != "" {
+		header.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+
+	//清除http.Server设定的超时
+	netConn.SetDeadline(time.Time{})
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err := netConn.Write(buf.Bytes()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetWriteDeadline(time.Time{})
+
+	c.Abort()
+	conn := &WSConn{
+		conn:        netConn,
+		br:          brw.Reader,
+		subprotocol: subprotocol,
+	}
+	conn.SetReadLimit(u.ReadLimit)
+	return conn, nil
+}
+
+//upgradeFail 以code拒绝握手并中断处理链
+func (c *Context) upgradeFail(code int, reason string) error {
+	c.AbortWithStatusJSON(code, H{"message": reason})
+	return errors.New("wego: websocket: " + reason)
+}
+
+func (u *Upgrader) selectSubprotocol(req *http.Request) string {
+	for _, values := range req.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(values, ",") {
+			protocol = strings.TrimSpace(protocol)
+			for _, supported := range u.Subprotocols {
+				if protocol == supported {
+					return protocol
+				}
+			}
+		}
+	}
+	return ""
+}
+
+//sameOrigin 未设置Origin或Origin与Host相同时返回true
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host)
+}
+
+//headerContainsToken 判断以 , 分隔的请求头中是否包含token,忽略大小写
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+//WSConn 为升级后的websocket连接
+//	同一时刻只能有一个goroutine读取,写入方法可以被多个goroutine并发调用
+//	读取时自动回复ping与关闭帧,因此即使只写入也需要有goroutine持续读取
+type WSConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+
+	readMu      sync.Mutex
+	readLimit   int64
+	readErr     error
+	pingHandler func(data string) error
+	pongHandler func(data string) error
+
+	msgMu         sync.Mutex //保证分片消息的帧不与其他数据帧交错
+	writeMu       sync.Mutex //保证每一帧完整写入
+	writeDeadline time.Time
+	closeSent     bool
+}
+
+//Subprotocol 返回协商得到的子协议
+func (conn *WSConn) Subprotocol() string {
+	return conn.subprotocol
+}
+
+//RemoteAddr 返回对方的网络地址
+func (conn *WSConn) RemoteAddr() net.Addr {
+	return conn.conn.RemoteAddr()
+}
+
+//SetReadLimit 设定单条消息的最大字节数,小于等于0时使用默认的1MB
+//	消息的长度来自客户端,必须限制以防止分配过多的内存
+func (conn *WSConn) SetReadLimit(limit int64) {
+	if limit <= 0 {
+		limit = defaultWSReadLimit
+	}
+	conn.readLimit = limit
+}
+
+//SetReadDeadline 设定读取的超时时间,超时后连接不能再使用
+func (conn *WSConn) SetReadDeadline(t time.Time) error {
+	return conn.conn.SetReadDeadline(t)
+}
+
+//SetWriteDeadline 设定写入数据帧的超时时间
+func (conn *WSConn) SetWriteDeadline(t time.Time) error {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	conn.writeDeadline = t
+	return nil
+}
+
+//SetPingHandler 设定收到ping时的处理函数,默认回复内容相同的pong
+func (conn *WSConn) SetPingHandler(h func(data string) error) {
+	conn.pingHandler = h
+}
+
+//SetPongHandler 设定收到pong时的处理函数,常用于延长读取超时时间
+func (conn *WSConn) SetPongHandler(h func(data string) error) {
+	conn.pongHandler = h
+}
+
+//ReadMessage 读取一条完整的消息,分片的消息会被合并,期间收到的控制帧会被自动处理
+//	对方关闭连接时返回 *CloseError,发生错误后再次调用会返回同样的错误
+func (conn *WSConn) ReadMessage() (messageType int, data []byte, err error) {
+	conn.readMu.Lock()
+	defer conn.readMu.Unlock()
+	if conn.readErr != nil {
+		return 0, nil, conn.readErr
+	}
+	for {
+		fin, opcode, payload, err := conn.readFrame(int64(len(data)))
+		if err != nil {
+			return 0, nil, conn.readFail(err)
+		}
+		switch opcode {
+		case PingMessage:
+			if err := conn.handlePing(payload); err != nil {
+				return 0, nil, conn.readFail(err)
+			}
+			continue
+		case PongMessage:
+			if conn.pongHandler != nil {
+				if err := conn.pongHandler(string(payload)); err != nil {
+					return 0, nil, conn.readFail(err)
+				}
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, conn.readFail(conn.handleClose(payload))
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, conn.readFail(&wsViolation{CloseProtocolError, "unexpected continuation frame"})
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, conn.readFail(&wsViolation{CloseProtocolError, "expected continuation frame"})
+			}
+			messageType = opcode
+		default:
+			return 0, nil, conn.readFail(&wsViolation{CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode)})
+		}
+		data = append(data, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(data) {
+				return 0, nil, conn.readFail(&wsViolation{CloseInvalidFramePayloadData, "invalid UTF-8 in text message"})
+			}
+			return messageType, data, nil
+		}
+	}
+}
+
+//ReadJSON 读取一条消息并解析为json
+func (conn *WSConn) ReadJSON(v interface{}) error {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+//readFrame 读取一帧,received为当前消息已经读取的字节数
+func (conn *WSConn) readFrame(received int64) (fin bool, opcode int, payload []byte, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(conn.br, header[:2]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		err = &wsViolation{CloseProtocolError, "reserved bits are set"}
+		return
+	}
+	if header[1]&0x80 == 0 {
+		err = &wsViolation{CloseProtocolError, "client frames must be masked"}
+		return
+	}
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(conn.br, header[:2]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err = io.ReadFull(conn.br, header[:8]); err != nil {
+			return
+		}
+		if header[0]&0x80 != 0 {
+			err = &wsViolation{CloseProtocolError, "invalid payload length"}
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(header[:8]))
+	}
+	if opcode >= CloseMessage {
+		if !fin || length > maxControlPayload {
+			err = &wsViolation{CloseProtocolError, "invalid control frame"}
+			return
+		}
+	} else if received+length > conn.readLimit {
+		err = &wsViolation{CloseMessageTooBig, "message exceeds the read limit"}
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(conn.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(conn.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+//readFail 记录读取错误,对方违反协议时使用对应的关闭码关闭连接
+func (conn *WSConn) readFail(err error) error {
+	if v, ok := err.(*wsViolation); ok {
+		conn.WriteClose(v.code, v.text)
+	}
+	conn.readErr = err
+	return err
+}
+
+func (conn *WSConn) handlePing(payload []byte) error {
+	if conn.pingHandler != nil {
+		return conn.pingHandler(string(payload))
+	}
+	err := conn.WriteControl(PongMessage, payload, time.Now().Add(time.Second))
+	if err == ErrCloseSent {
+		return nil
+	}
+	return err
+}
+
+//handleClose 解析关闭帧并回复相同的关闭码
+func (conn *WSConn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	if len(payload) == 1 {
+		return &wsViolation{CloseProtocolError, "invalid close frame"}
+	}
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return &wsViolation{CloseProtocolError, fmt.Sprintf("invalid close code %d", closeErr.Code)}
+		}
+		if !utf8.Valid(payload[2:]) {
+			return &wsViolation{CloseInvalidFramePayloadData, "invalid UTF-8 in close frame"}
+		}
+	}
+	var reply []byte
+	if closeErr.Code != CloseNoStatusReceived {
+		reply = FormatCloseMessage(closeErr.Code, "")
+	}
+	if err := conn.WriteControl(CloseMessage, reply, time.Now().Add(time.Second)); err != nil && err != ErrCloseSent {
+		return err
+	}
+	return closeErr
+}
+
+//validCloseCode 判断关闭帧中的关闭码是否可以使用
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+//FormatCloseMessage 生成关闭帧的载荷,text过长时被截断
+func FormatCloseMessage(code int, text string) []byte {
+	if len(text) > maxControlPayload-2 {
+		text = text[:maxControlPayload-2]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	data := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(data, uint16(code))
+	copy(data[2:], text)
+	return data
+}
+
+//WriteMessage 以一帧发送一条文本或二进制消息
+func (conn *WSConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return conn.WriteControl(messageType, data, time.Now().Add(time.Second))
+	}
+	conn.msgMu.Lock()
+	defer conn.msgMu.Unlock()
+	return conn.writeFrame(messageType, true, data, time.Time{})
+}
+
+//WriteJSON 将v编码为json后作为文本消息发送
+func (conn *WSConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(TextMessage, data)
+}
+
+//NextWriter 返回分片发送一条消息的Writer,每次Write发送一帧,Close时发送最后一帧
+//	Close之前其他goroutine发送数据消息会被阻塞,控制帧不受影响
+func (conn *WSConn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return nil, fmt.Errorf("wego: websocket: invalid message type %d", messageType)
+	}
+	conn.msgMu.Lock()
+	return &wsMessageWriter{conn: conn, opcode: messageType}, nil
+}
+
+type wsMessageWriter struct {
+	conn   *WSConn
+	opcode int
+	closed bool
+}
+
+func (w *wsMessageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("wego: websocket: write to closed message writer")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.conn.writeFrame(w.opcode, false, p, time.Time{}); err != nil {
+		return 0, err
+	}
+	w.opcode = continuationFrame
+	return len(p), nil
+}
+
+func (w *wsMessageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.conn.msgMu.Unlock()
+	return w.conn.writeFrame(w.opcode, true, nil, time.Time{})
+}
+
+//WriteControl 在deadline之前发送ping、pong或关闭帧
+func (conn *WSConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("wego: websocket: invalid control message type %d", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("wego: websocket: control frame payload too large")
+	}
+	return conn.writeFrame(messageType, true, data, deadline)
+}
+
+//WriteClose 发送关闭帧,之后应继续读取直到收到对方的关闭帧
+func (conn *WSConn) WriteClose(code int, text string) error {
+	return conn.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(time.Second))
+}
+
+//writeFrame 发送一帧,服务端发送的帧不使用掩码,deadline为零值时使用 SetWriteDeadline 设定的时间
+func (conn *WSConn) writeFrame(opcode int, fin bool, payload []byte, deadline time.Time) error {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	if conn.closeSent {
+		return ErrCloseSent
+	}
+	if deadline.IsZero() {
+		deadline = conn.writeDeadline
+	}
+	conn.conn.SetWriteDeadline(deadline)
+
+	frame := make([]byte, 0, 10+len(payload))
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame = append(frame, b0)
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 126, byte(length>>8), byte(length))
+	default:
+		frame = append(frame, 127)
+		frame = append(frame, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(frame[len(frame)-8:], uint64(length))
+	}
+	frame = append(frame, payload...)
+	if opcode == CloseMessage {
+		conn.closeSent = true
+	}
+	_, err := conn.conn.Write(frame)
+	return err
+}
+
+//Close 未发送关闭帧时以1000发送关闭帧,然后关闭底层连接
+func (conn *WSConn) Close() error {
+	conn.WriteClose(CloseNormalClosure, "")
+	return conn.conn.Close()
+}
@@ -0,0 +1,199 @@
+package wego
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+//wsClient 为测试使用的最简websocket客户端
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, srv *httptest.Server, path string, header http.Header) (*wsClient, *http.Response) {
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wsClient{t: t, conn: conn, br: br}, resp
+}
+
+func (c *wsClient) writeFrame(fin bool, opcode int, payload []byte) {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	if len(payload) <= 125 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsClient) readFrame() (int, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return int(header[0] & 0x0f), payload
+}
+
+func (c *wsClient) expectClose(code int) {
+	opcode, payload := c.readFrame()
+	if opcode != CloseMessage || len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code {
+		c.t.Fatalf("expected close frame with code %d, got opcode %d %q", code, opcode, payload)
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	r := New()
+	r.WebSocket.ReadLimit = 1024
+	r.WebSocket.Subprotocols = []string{"chat"}
+	var closeErr error
+	done := make(chan struct{})
+	api := r.Group("/api", func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		}
+	})
+	api.WS("/echo", func(c *Context, conn *WSConn) {
+		defer close(done)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				closeErr = err
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	//中间件在升级之前执行
+	_, resp := dialWS(t, srv, "/api/echo", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("middleware should reject the handshake, got %d", resp.StatusCode)
+	}
+	_, resp = dialWS(t, srv, "/api/echo?token=secret", http.Header{"Origin": {"http://evil.example"}})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross origin handshake should be rejected, got %d", resp.StatusCode)
+	}
+
+	client, resp := dialWS(t, srv, "/api/echo?token=secret", http.Header{"Sec-Websocket-Protocol": {"v2, chat"}})
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" ||
+		resp.Header.Get("Sec-WebSocket-Protocol") != "chat" {
+		t.Fatalf("unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	client.writeFrame(true, TextMessage, []byte("hello"))
+	if opcode, payload := client.readFrame(); opcode != TextMessage || string(payload) != "hello" {
+		t.Fatalf("unexpected echo %d %q", opcode, payload)
+	}
+
+	//分片消息之间可以插入控制帧
+	client.writeFrame(false, BinaryMessage, []byte("frag"))
+	client.writeFrame(true, PingMessage, []byte("p"))
+	if opcode, payload := client.readFrame(); opcode != PongMessage || string(payload) != "p" {
+		t.Fatalf("expected pong, got %d %q", opcode, payload)
+	}
+	client.writeFrame(true, continuationFrame, []byte("ment"))
+	if opcode, payload := client.readFrame(); opcode != BinaryMessage || string(payload) != "fragment" {
+		t.Fatalf("unexpected echo %d %q", opcode, payload)
+	}
+
+	//超出大小限制的消息
+	client.writeFrame(true, TextMessage, []byte(strings.Repeat("a", 2048)))
+	client.expectClose(CloseMessageTooBig)
+	<-done
+	if closeErr == nil || !strings.Contains(closeErr.Error(), "read limit") {
+		t.Fatalf("expected read limit error, got %v", closeErr)
+	}
+
+	//对方关闭连接时回复相同的关闭码
+	done = make(chan struct{})
+	client, _ = dialWS(t, srv, "/api/echo?token=secret", nil)
+	client.writeFrame(true, CloseMessage, FormatCloseMessage(CloseGoingAway, "bye"))
+	client.expectClose(CloseGoingAway)
+	<-done
+	if e, ok := closeErr.(*CloseError); !ok || e.Code != CloseGoingAway || e.Text != "bye" {
+		t.Fatalf("expected CloseError, got %v", closeErr)
+	}
+
+	//违反协议时以1002关闭
+	done = make(chan struct{})
+	client, _ = dialWS(t, srv, "/api/echo?token=secret", nil)
+	client.writeFrame(true, continuationFrame, []byte("x"))
+	client.expectClose(CloseProtocolError)
+	<-done
+}
+
+func TestWebSocketDefaultReadLimit(t *testing.T) {
+	r := New()
+	done := make(chan error, 1)
+	r.WS("/ws", func(c *Context, conn *WSConn) {
+		//不限制时同样使用默认的大小限制
+		conn.SetReadLimit(0)
+		_, _, err := conn.ReadMessage()
+		done <- err
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	client, _ := dialWS(t, srv, "/ws", nil)
+	//声明长度为1TB的帧,不应按该长度分配内存
+	header := []byte{0x80 | BinaryMessage, 0x80 | 127, 0, 0, 1, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+	if _, err := client.conn.Write(header); err != nil {
+		t.Fatal(err)
+	}
+	client.expectClose(CloseMessageTooBig)
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "read limit") {
+		t.Fatalf("expected read limit error, got %v", err)
+	}
+}
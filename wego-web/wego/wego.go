@@ -2,10 +2,11 @@ package wego
 
 import (
 	"html/template"
-	"log"
+	"io/fs"
 	"net/http"
-	"path"
 	"strings"
+	"sync"
+	"time"
 )
 
 //HandlerFunc 被引擎使用的请求处理器的类型
@@ -15,24 +16,66 @@ type HandlerFunc func(*Context)
 type (
 	RouterGroup struct {
 		prefix      string        //支持嵌套
-		middlewares []HandlerFunc //支持中间件
+		middlewares []HandlerFunc //支持中间件,包含创建时继承自父组的中间件
 		engine      *Engine       //所有的组使用同一个Engine实例
 	}
 
 	Engine struct {
-		*RouterGroup  //继承RouterGroup,将Engine抽象为最高层的RouterGroup
-		router        *router
-		groups        []*RouterGroup     //存储所有的groups
-		htmlTemplates *template.Template //http模板
-		funcMap       template.FuncMap   //html模板渲染函数
+		*RouterGroup //继承RouterGroup,将Engine抽象为最高层的RouterGroup
+		router       *router
+		pool         sync.Pool        //Context对象池
+		templates    *HTMLTemplates   //LoadHTML系列方法加载的模板
+		funcMap      template.FuncMap //html模板渲染函数
+		noRoute      []HandlerFunc    //未匹配到路由时的处理链
+		noMethod     []HandlerFunc    //请求方式不匹配时的处理链
+
+		//HTMLRender 为 Context.HTMLTemplate 使用的模板引擎,LoadHTML系列方法会将其设为内置的 HTMLTemplates
+		HTMLRender HTMLRender
+
+		//RedirectTrailingSlash 路径未匹配但增加或去掉末尾的 / 后可以匹配时重定向,默认开启
+		RedirectTrailingSlash bool
+		//RedirectFixedPath 路径未匹配时尝试去除多余的 / 、 . 、 .. 并忽略大小写匹配,成功则重定向,默认关闭
+		RedirectFixedPath bool
+
+		//SecureJSONPrefix 为 Context.SecureJSON 返回json数组时添加的前缀,默认为 while(1);
+		SecureJSONPrefix string
+
+		//MaxMultipartMemory 为解析multipart表单时使用的内存上限,超出的文件保存在临时文件中,默认32MB
+		MaxMultipartMemory int64
+		//MaxRequestBodySize 为请求体的最大字节数,为0时不限制
+		//	Content-Length超出时直接返回413,否则读取超出的部分时返回 ErrBodyTooLarge
+		MaxRequestBodySize int64
+
+		//Log 记录 Logger 中间件的访问日志以及引擎自身的日志,默认为文本格式,输出到log包当前的输出
+		Log *AccessLogger
+
+		//WebSocket 为 Context.Upgrade 与 RouterGroup.WS 使用的握手设定
+		WebSocket Upgrader
+
+		//服务器配置,需要在调用Run系列方法之前设定,为0时不限制,含义与http.Server中的同名字段相同
+		ReadTimeout       time.Duration
+		ReadHeaderTimeout time.Duration
+		WriteTimeout      time.Duration
+		IdleTimeout       time.Duration
+		MaxHeaderBytes    int
+
+		state serverState //运行中的服务器与关闭时执行的钩子
 	}
 )
 
 //New 是wego.Engine的构造器
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
-	engine.RouterGroup = &RouterGroup{engine: engine}  //新建引擎所在的group
-	engine.groups = []*RouterGroup{engine.RouterGroup} //将引擎所在的group加入groups中
+	engine := &Engine{
+		router:                newRouter(),
+		RedirectTrailingSlash: true,
+		SecureJSONPrefix:      "while(1);",
+		MaxMultipartMemory:    defaultMultipartMemory,
+		Log:                   NewAccessLogger(LogConfig{}),
+	}
+	engine.RouterGroup = &RouterGroup{engine: engine} //新建引擎所在的group
+	engine.pool.New = func() interface{} {
+		return newContext(engine)
+	}
 	return engine
 }
 
@@ -45,88 +88,175 @@ func Default() *Engine {
 
 //Group 用于在当前group下创建一个新的子RouterGroup
 //所有的group共享同一个 Engine instance
-func (group *RouterGroup) Group(prefix string) *RouterGroup {
-	engine := group.engine
-	newGroup := &RouterGroup{
-		prefix: group.prefix + prefix,
-		engine: engine,
+//	子组在创建时继承父组当前已有的中间件,之后再向父组添加的中间件不会影响子组
+func (group *RouterGroup) Group(prefix string, middlewares ...HandlerFunc) *RouterGroup {
+	return &RouterGroup{
+		prefix:      group.prefix + prefix,
+		middlewares: group.combineHandlers(middlewares),
+		engine:      group.engine,
 	}
-	engine.groups = append(engine.groups, newGroup)
-	return newGroup
+}
+
+//combineHandlers 将组的中间件与handlers合并为一条新的处理链
+func (group *RouterGroup) combineHandlers(handlers []HandlerFunc) []HandlerFunc {
+	merged := make([]HandlerFunc, 0, len(group.middlewares)+len(handlers))
+	merged = append(merged, group.middlewares...)
+	return append(merged, handlers...)
 }
 
 //addRoute 内部添加Route接口,不向外暴露
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+//	路由的处理链(组中间件+handlers)在注册时确定,请求时不再重新计算
+func (group *RouterGroup) addRoute(method string, comp string, handlers []HandlerFunc) *node {
+	if len(handlers) == 0 {
+		panic("wego: there must be at least one handler for " + method + " " + group.prefix + comp)
+	}
 	pattern := group.prefix + comp
-	log.Printf("Route %4s - %s", method, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	group.engine.Log.Printf("Route %4s - %s", method, pattern)
+	return group.engine.router.addRoute(method, pattern, group.combineHandlers(handlers))
+}
+
+//anyMethods Any 注册的所有请求方式
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+	http.MethodConnect, http.MethodTrace,
+}
+
+//Handle 使用指定的请求方式添加路由,可用于注册非常用的请求方式
+func (group *RouterGroup) Handle(method string, pattern string, handlers ...HandlerFunc) *Route {
+	if method == "" || strings.ToUpper(method) != method {
+		panic("wego: invalid http method: " + method)
+	}
+	return group.route(group.addRoute(method, pattern, handlers))
 }
 
 //GET 定义了添加GET请求的方法
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return group.route(group.addRoute(http.MethodGet, pattern, handlers))
 }
 
 //POST 定义了添加POST请求的方法
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return group.route(group.addRoute(http.MethodPost, pattern, handlers))
+}
+
+//PUT 定义了添加PUT请求的方法
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.route(group.addRoute(http.MethodPut, pattern, handlers))
+}
+
+//PATCH 定义了添加PATCH请求的方法
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return group.route(group.addRoute(http.MethodPatch, pattern, handlers))
+}
+
+//DELETE 定义了添加DELETE请求的方法
+func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.route(group.addRoute(http.MethodDelete, pattern, handlers))
+}
+
+//HEAD 定义了添加HEAD请求的方法
+//	未注册HEAD路由时,HEAD请求由对应的GET路由响应
+func (group *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return group.route(group.addRoute(http.MethodHead, pattern, handlers))
+}
+
+//OPTIONS 定义了添加OPTIONS请求的方法
+//	未注册OPTIONS路由时,OPTIONS请求会被自动响应并返回Allow头
+func (group *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return group.route(group.addRoute(http.MethodOptions, pattern, handlers))
 }
 
-func (engine *Engine) Run(addr string) (err error) {
-	return http.ListenAndServe(addr, engine)
+//Any 为所有请求方式添加同一个处理器
+func (group *RouterGroup) Any(pattern string, handlers ...HandlerFunc) *Route {
+	nodes := make([]*node, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		nodes = append(nodes, group.addRoute(method, pattern, handlers))
+	}
+	return group.route(nodes...)
 }
 
 //Use 为当前组添加需要使用的中间件
+//	中间件只作用于之后在该组及之后创建的子组中注册的路由
+//	Engine上的中间件(全局中间件)还会作用于404/405等未匹配到路由的请求
 func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
+//NoRoute 设定未匹配到路由时的处理器,处理器在全局中间件之后执行,执行前状态码已设为404
+func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
+	engine.noRoute = handlers
+}
+
+//NoMethod 设定路径存在但请求方式不匹配时的处理器,执行前已设定状态码405与Allow响应头
+//	未注册OPTIONS路由时仍然自动响应OPTIONS请求
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.noMethod = handlers
+}
+
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middlewares []HandlerFunc
-	for _, group := range engine.groups {
-		//只要存在组对应的前缀,则将组对应的中间件加入该上下文需要使用的中间件
-		//组的嵌套使用中间件在此处实现
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
-		}
-	}
-	//封装后转交给router处理
-	c := newContext(w, req)
-	c.handlers = middlewares
-	c.engine = engine
+	//从对象池中取出Context封装后转交给router处理,处理链由router根据匹配结果设定
+	c := engine.pool.Get().(*Context)
+	c.reset(w, req)
 	engine.router.handle(c)
+	//处理器只设定了状态码而没有写入响应体时发送响应头
+	c.Writer.WriteHeaderNow()
+	//中间件替换了c.Req时,net/http只会删除原请求的multipart临时文件
+	removeMultipartForm(c.Req.MultipartForm, req.MultipartForm)
+	engine.pool.Put(c)
 }
 
-func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	//解析请求的地址，映射到服务器上文件的真实地址，交给http.FileServer处理
-	absolutePath := path.Join(group.prefix, relativePath)
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	return func(c *Context) {
-		file := c.Param("filePath")
-		//检查文件是否存在或是否有权限读取
-		if _, err := fs.Open(file); err != nil {
-			c.Status(http.StatusNotFound)
-			return
+//SetFuncMap 设定html模板中可以使用的函数,已经加载的模板会被重新解析
+//	模板中总是可以使用 url 函数生成命名路由的url: {{ url "user.show" "id" .ID }}
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
+	engine.funcMap = funcMap
+	if engine.templates != nil {
+		if err := engine.templates.SetFuncs(engine.templateFuncs()); err != nil {
+			panic(err)
 		}
-
-		fileServer.ServeHTTP(c.Writer, c.Req)
 	}
 }
 
-//Static 将服务器上的静态资源映射到url中
-//	Static("/assets", "/static")
-//	访问/asserts/xxxfile 即可返回 /static/xxxfile
-func (group *RouterGroup) Static(relativePath string, root string) {
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
-	urlPattern := path.Join(relativePath, "/*filepath")
-	//注册GET处理器
-	group.GET(urlPattern, handler)
+//templateFuncs 返回模板可以使用的函数,包含内置的url函数与 SetFuncMap 设定的函数
+func (engine *Engine) templateFuncs() template.FuncMap {
+	funcs := template.FuncMap{"url": engine.URL}
+	for name, fn := range engine.funcMap {
+		funcs[name] = fn
+	}
+	return funcs
 }
 
-func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
-	engine.funcMap = funcMap
+//htmlTemplates 返回引擎内置的 HTMLTemplates ,并将其设为 HTMLRender
+func (engine *Engine) htmlTemplates() *HTMLTemplates {
+	if engine.templates == nil {
+		engine.templates = NewHTMLTemplates(engine.templateFuncs())
+	}
+	engine.HTMLRender = engine.templates
+	return engine.templates
 }
 
+//LoadHTMLGlob 加载磁盘上匹配pattern的模板,渲染时使用模板名称(默认为文件名),解析失败时panic
 func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+	if err := engine.htmlTemplates().LoadGlob(nil, pattern); err != nil {
+		panic(err)
+	}
+}
+
+//LoadHTMLFS 从fsys(如embed.FS)中加载匹配patterns的模板,解析失败时panic
+func (engine *Engine) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	if err := engine.htmlTemplates().LoadGlob(fsys, patterns...); err != nil {
+		panic(err)
+	}
+}
+
+//LoadHTMLTemplates 加载使用布局的模板集,渲染时使用页面的路径作为名称,解析失败时panic
+//	r.LoadHTMLTemplates(wego.TemplateSet{Layouts: []string{"templates/layouts/*.html"}, Pages: []string{"templates/pages/*.html"}, Layout: "base.html"})
+//	c.HTMLTemplate(http.StatusOK, "templates/pages/index.html", data)
+func (engine *Engine) LoadHTMLTemplates(sets ...TemplateSet) {
+	t := engine.htmlTemplates()
+	for _, set := range sets {
+		if err := t.Load(set); err != nil {
+			panic(err)
+		}
+	}
 }
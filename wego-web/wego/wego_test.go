@@ -1,6 +1,11 @@
 package wego
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
 
 func TestNestedGroup(t *testing.T) {
 	r := New()
@@ -14,3 +19,59 @@ func TestNestedGroup(t *testing.T) {
 		t.Fatal("v3 prefix should be /v1/v2/v3")
 	}
 }
+
+func TestGroupMiddlewareScope(t *testing.T) {
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	r := New()
+	r.Use(mark("global"))
+	v1 := r.Group("/v1")
+	v1.Use(mark("v1"))
+	v1.GET("/a", mark("a"))
+	//在路由注册之后添加的中间件不影响已注册的路由
+	v1.Use(mark("late"))
+	v1.GET("/b", mark("b"))
+	r.GET("/v10/a", mark("v10"))
+
+	cases := map[string]string{
+		"/v1/a":  "global,v1,a",
+		"/v1/b":  "global,v1,late,b",
+		"/v10/a": "global,v10",
+		"/v1/c":  "global",
+	}
+	for path, want := range cases {
+		trace = nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if got := strings.Join(trace, ","); got != want {
+			t.Fatalf("%s: handlers should be %s, got %s", path, want, got)
+		}
+	}
+}
+
+func TestTypedParams(t *testing.T) {
+	r := New()
+	r.GET("/users/:id<int>/:uid<uuid>", func(c *Context) {
+		id, err1 := c.ParamInt("id")
+		uid, err2 := c.ParamUUID("uid")
+		if err1 != nil || err2 != nil {
+			c.String(http.StatusBadRequest, "%v %v", err1, err2)
+			return
+		}
+		c.String(http.StatusOK, "%d %s", id, uid)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/12/0F6A2C3E-1111-4A4B-9C9D-0123456789AB", nil))
+	if w.Body.String() != "12 0f6a2c3e-1111-4a4b-9c9d-0123456789ab" {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/abc/0F6A2C3E-1111-4A4B-9C9D-0123456789AB", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("constraint mismatch should be 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package wego
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//marshalYAML 将v编码为yaml,只支持返回数据所需的常见类型
+//	结构体字段使用 yaml 标签命名,支持 omitempty 与 - ,未设置标签时使用小写的字段名
+//	map的键按字典序排列,实现了encoding.TextMarshaler的类型按字符串编码
+func marshalYAML(v interface{}) ([]byte, error) {
+	e := &yamlEncoder{}
+	if err := e.encode(reflect.ValueOf(v), 0, true); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type yamlEncoder struct {
+	buf bytes.Buffer
+}
+
+//yamlEntry 为map或结构体中的一项
+type yamlEntry struct {
+	key   string
+	value reflect.Value
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+//indirect 解开指针与接口,nil时返回无效的Value
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.Type().Implements(textMarshalerType) && v.Kind() == reflect.Ptr && !v.IsNil() {
+			return v
+		}
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+//encode 编码v并换行,first为true时不输出第一行的缩进(用于列表项 "- " 之后)
+func (e *yamlEncoder) encode(v reflect.Value, indent int, first bool) error {
+	v = indirect(v)
+	scalar, ok, err := yamlScalar(v)
+	if err != nil {
+		return err
+	}
+	if ok {
+		e.buf.WriteString(scalar)
+		e.buf.WriteByte('\n')
+		return nil
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 || !first {
+				e.writeIndent(indent)
+			}
+			e.buf.WriteString("- ")
+			if err := e.encode(v.Index(i), indent+2, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	entries, err := yamlEntries(v)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		if i > 0 || !first {
+			e.writeIndent(indent)
+		}
+		e.buf.WriteString(quoteYAML(entry.key))
+		e.buf.WriteByte(':')
+		value := indirect(entry.value)
+		scalar, ok, err := yamlScalar(value)
+		if err != nil {
+			return err
+		}
+		if ok {
+			e.buf.WriteByte(' ')
+			e.buf.WriteString(scalar)
+			e.buf.WriteByte('\n')
+			continue
+		}
+		e.buf.WriteByte('\n')
+		childIndent := indent + 2
+		if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+			//列表项与键对齐
+			childIndent = indent
+		}
+		if err := e.encode(value, childIndent, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *yamlEncoder) writeIndent(indent int) {
+	for i := 0; i < indent; i++ {
+		e.buf.WriteByte(' ')
+	}
+}
+
+//yamlScalar 将标量以及空的map、结构体、列表编码为一行,v不是标量时ok为false
+func yamlScalar(v reflect.Value) (string, bool, error) {
+	if !v.IsValid() {
+		return "null", true, nil
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), true, nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", false, err
+		}
+		return quoteYAML(string(text)), true, nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true, nil
+	case reflect.String:
+		return quoteYAML(v.String()), true, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return quoteYAML(string(v.Bytes())), true, nil
+		}
+		if v.Len() == 0 {
+			return "[]", true, nil
+		}
+		return "", false, nil
+	case reflect.Map:
+		if v.Len() == 0 {
+			return "{}", true, nil
+		}
+		return "", false, nil
+	case reflect.Struct:
+		entries, err := yamlEntries(v)
+		if err != nil {
+			return "", false, err
+		}
+		if len(entries) == 0 {
+			return "{}", true, nil
+		}
+		return "", false, nil
+	}
+	return "", false, fmt.Errorf("wego: yaml: unsupported type %s", v.Type())
+}
+
+//yamlEntries 返回map或结构体中需要编码的项
+func yamlEntries(v reflect.Value) ([]yamlEntry, error) {
+	var entries []yamlEntry
+	switch v.Kind() {
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			entries = append(entries, yamlEntry{key: fmt.Sprint(iter.Key().Interface()), value: iter.Value()})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name, opts := splitTag(field.Tag.Get("yaml"))
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			fv := v.Field(i)
+			if _, omit := opts["omitempty"]; omit && isEmptyValue(fv) {
+				continue
+			}
+			entries = append(entries, yamlEntry{key: name, value: fv})
+		}
+	default:
+		return nil, fmt.Errorf("wego: yaml: unsupported type %s", v.Type())
+	}
+	return entries, nil
+}
+
+//quoteYAML 字符串可能被解析为其他类型或包含特殊字符时使用双引号
+func quoteYAML(s string) string {
+	if s == "" {
+		return `""`
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n", ".nan", ".inf", "-.inf":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	//以数字、+ 、 . 开头的字符串可能被解析为其他格式的数字(如 0x1F 、 1_000 、 1:20 、 .Inf)或日期
+	if s[0] >= '0' && s[0] <= '9' || s[0] == '+' || s[0] == '.' || s == "<<" || s == "=" {
+		return strconv.Quote(s)
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@` ") || strings.HasSuffix(s, " ") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return strconv.Quote(s)
+	}
+	for _, ch := range s {
+		if ch < 0x20 || ch == 0x7f || ch == '\u2028' || ch == '\u2029' || ch == '\ufeff' {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}